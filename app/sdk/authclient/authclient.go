@@ -14,6 +14,8 @@ import (
 
 	"github.com/nutchapon-m/web-server/app/sdk/errs"
 	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/tracer"
+	"github.com/nutchapon-m/web-server/foundation/web"
 )
 
 // This provides a default client configuration, but it's recommended
@@ -81,6 +83,19 @@ func (cln *Client) Authenticate(ctx context.Context, authorization string) (Auth
 	return resp, nil
 }
 
+// JWKS calls the auth service to fetch the JSON Web Key Set signing its
+// tokens. It can be used by jwt.NewRemoteKeySet to verify tokens locally.
+func (cln *Client) JWKS(ctx context.Context) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/.well-known/jwks.json", cln.url)
+
+	var resp json.RawMessage
+	if err := cln.do(ctx, http.MethodGet, endpoint, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 // Authorize calls the auth service to authorize the user.
 func (cln *Client) Authorize(ctx context.Context, auth Authorize) error {
 	endpoint := fmt.Sprintf("%s/v1/auth/authorize", cln.url)
@@ -101,9 +116,17 @@ func (cln *Client) do(ctx context.Context, method string, endpoint string, heade
 	}
 	base := path.Base(u.Path)
 
+	ctx, span := tracer.Start(ctx, "authclient "+base, tracer.SpanKindClient)
+	span.SetAttributes("http.method", method, "url.full", endpoint)
+
 	cln.log.Info(ctx, "authclient: rawRequest: started", "method", method, "call", base, "endpoint", endpoint)
 	defer func() {
 		cln.log.Info(ctx, "authclient: rawRequest: completed", "status", statusCode)
+		span.SetAttributes("http.status_code", statusCode)
+		if statusCode >= http.StatusInternalServerError {
+			span.SetStatus(tracer.StatusError, http.StatusText(statusCode))
+		}
+		span.End()
 	}()
 
 	var b bytes.Buffer
@@ -121,6 +144,10 @@ func (cln *Client) do(ctx context.Context, method string, endpoint string, heade
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	if id := web.RequestID(ctx); id != "" {
+		req.Header.Set(web.RequestIDHeader, id)
+	}
+	tracer.Inject(ctx, req.Header)
 	for key, value := range headers {
 		cln.log.Info(ctx, "authclient: rawRequest", "key", key, "value", value)
 		req.Header.Set(key, value)
@@ -128,6 +155,7 @@ func (cln *Client) do(ctx context.Context, method string, endpoint string, heade
 
 	resp, err := cln.http.Do(req)
 	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("do: error: %w", err)
 	}
 	defer resp.Body.Close()
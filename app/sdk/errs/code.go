@@ -116,73 +116,101 @@ var (
 	// system has been broken. If you see one of these errors,
 	// something is very broken. The error message is not sent to the client.
 	InternalOnlyLog = ErrCode{value: 19}
+
+	// MethodNotAllowed indicates the resource exists but does not support
+	// the method of the request.
+	MethodNotAllowed = ErrCode{value: 20}
+
+	// PayloadTooLarge indicates the request body is larger than the limit
+	// accepted by the server.
+	PayloadTooLarge = ErrCode{value: 21}
+
+	// UnsupportedMediaType indicates the content type of the request body
+	// is not supported by the server.
+	UnsupportedMediaType = ErrCode{value: 22}
+
+	// NotAcceptable indicates the server can't produce a response in any of
+	// the media types accepted by the client.
+	NotAcceptable = ErrCode{value: 23}
 )
 
 var codeNumbers = map[string]ErrCode{
-	"ok":                  None,
-	"no_content":          NoContent,
-	"canceled":            Canceled,
-	"unknown":             Unknown,
-	"invalid_argument":    InvalidArgument,
-	"deadline_exceeded":   DeadlineExceeded,
-	"not_found":           NotFound,
-	"already_exists":      AlreadyExists,
-	"permission_denied":   PermissionDenied,
-	"resource_exhausted":  ResourceExhausted,
-	"failed_precondition": FailedPrecondition,
-	"aborted":             Aborted,
-	"out_of_range":        OutOfRange,
-	"unimplemented":       Unimplemented,
-	"internal":            Internal,
-	"unavailable":         Unavailable,
-	"data_loss":           DataLoss,
-	"unauthenticated":     Unauthenticated,
-	"too_many_requests":   TooManyRequests,
-	"internal_only_log":   InternalOnlyLog,
+	"ok":                     None,
+	"no_content":             NoContent,
+	"canceled":               Canceled,
+	"unknown":                Unknown,
+	"invalid_argument":       InvalidArgument,
+	"deadline_exceeded":      DeadlineExceeded,
+	"not_found":              NotFound,
+	"already_exists":         AlreadyExists,
+	"permission_denied":      PermissionDenied,
+	"resource_exhausted":     ResourceExhausted,
+	"failed_precondition":    FailedPrecondition,
+	"aborted":                Aborted,
+	"out_of_range":           OutOfRange,
+	"unimplemented":          Unimplemented,
+	"internal":               Internal,
+	"unavailable":            Unavailable,
+	"data_loss":              DataLoss,
+	"unauthenticated":        Unauthenticated,
+	"too_many_requests":      TooManyRequests,
+	"internal_only_log":      InternalOnlyLog,
+	"method_not_allowed":     MethodNotAllowed,
+	"payload_too_large":      PayloadTooLarge,
+	"unsupported_media_type": UnsupportedMediaType,
+	"not_acceptable":         NotAcceptable,
 }
 
 var codeNames = map[ErrCode]string{
-	None:               "ok",
-	NoContent:          "ok_no_content",
-	Canceled:           "canceled",
-	Unknown:            "unknown",
-	InvalidArgument:    "invalid_argument",
-	DeadlineExceeded:   "deadline_exceeded",
-	NotFound:           "not_found",
-	AlreadyExists:      "already_exists",
-	PermissionDenied:   "permission_denied",
-	ResourceExhausted:  "resource_exhausted",
-	FailedPrecondition: "failed_precondition",
-	Aborted:            "aborted",
-	OutOfRange:         "out_of_range",
-	Unimplemented:      "unimplemented",
-	Internal:           "internal",
-	Unavailable:        "unavailable",
-	DataLoss:           "data_loss",
-	Unauthenticated:    "unauthenticated",
-	TooManyRequests:    "too_many_requests",
-	InternalOnlyLog:    "internal_only_log",
+	None:                 "ok",
+	NoContent:            "ok_no_content",
+	Canceled:             "canceled",
+	Unknown:              "unknown",
+	InvalidArgument:      "invalid_argument",
+	DeadlineExceeded:     "deadline_exceeded",
+	NotFound:             "not_found",
+	AlreadyExists:        "already_exists",
+	PermissionDenied:     "permission_denied",
+	ResourceExhausted:    "resource_exhausted",
+	FailedPrecondition:   "failed_precondition",
+	Aborted:              "aborted",
+	OutOfRange:           "out_of_range",
+	Unimplemented:        "unimplemented",
+	Internal:             "internal",
+	Unavailable:          "unavailable",
+	DataLoss:             "data_loss",
+	Unauthenticated:      "unauthenticated",
+	TooManyRequests:      "too_many_requests",
+	InternalOnlyLog:      "internal_only_log",
+	MethodNotAllowed:     "method_not_allowed",
+	PayloadTooLarge:      "payload_too_large",
+	UnsupportedMediaType: "unsupported_media_type",
+	NotAcceptable:        "not_acceptable",
 }
 
 var httpStatus = map[ErrCode]int{
-	None:               http.StatusOK,
-	NoContent:          http.StatusNoContent,
-	Canceled:           http.StatusGatewayTimeout,
-	Unknown:            http.StatusInternalServerError,
-	InvalidArgument:    http.StatusBadRequest,
-	DeadlineExceeded:   http.StatusGatewayTimeout,
-	NotFound:           http.StatusNotFound,
-	AlreadyExists:      http.StatusConflict,
-	PermissionDenied:   http.StatusForbidden,
-	ResourceExhausted:  http.StatusTooManyRequests,
-	FailedPrecondition: http.StatusBadRequest,
-	Aborted:            http.StatusConflict,
-	OutOfRange:         http.StatusBadRequest,
-	Unimplemented:      http.StatusNotImplemented,
-	Internal:           http.StatusInternalServerError,
-	Unavailable:        http.StatusServiceUnavailable,
-	DataLoss:           http.StatusInternalServerError,
-	Unauthenticated:    http.StatusUnauthorized,
-	TooManyRequests:    http.StatusTooManyRequests,
-	InternalOnlyLog:    http.StatusInternalServerError,
+	None:                 http.StatusOK,
+	NoContent:            http.StatusNoContent,
+	Canceled:             http.StatusGatewayTimeout,
+	Unknown:              http.StatusInternalServerError,
+	InvalidArgument:      http.StatusBadRequest,
+	DeadlineExceeded:     http.StatusGatewayTimeout,
+	NotFound:             http.StatusNotFound,
+	AlreadyExists:        http.StatusConflict,
+	PermissionDenied:     http.StatusForbidden,
+	ResourceExhausted:    http.StatusTooManyRequests,
+	FailedPrecondition:   http.StatusBadRequest,
+	Aborted:              http.StatusConflict,
+	OutOfRange:           http.StatusBadRequest,
+	Unimplemented:        http.StatusNotImplemented,
+	Internal:             http.StatusInternalServerError,
+	Unavailable:          http.StatusServiceUnavailable,
+	DataLoss:             http.StatusInternalServerError,
+	Unauthenticated:      http.StatusUnauthorized,
+	TooManyRequests:      http.StatusTooManyRequests,
+	InternalOnlyLog:      http.StatusInternalServerError,
+	MethodNotAllowed:     http.StatusMethodNotAllowed,
+	PayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	NotAcceptable:        http.StatusNotAcceptable,
 }
@@ -50,10 +50,11 @@ func (ec ErrCode) Equal(ec2 ErrCode) bool {
 
 // Error represents an error in the system.
 type Error struct {
-	Code     ErrCode `json:"code"`
-	Message  string  `json:"message"`
-	FuncName string  `json:"-"`
-	FileName string  `json:"-"`
+	Code      ErrCode `json:"code"`
+	Message   string  `json:"message"`
+	RequestID string  `json:"request_id,omitempty"`
+	FuncName  string  `json:"-"`
+	FileName  string  `json:"-"`
 }
 
 // New constructs an error based on an app error.
@@ -123,10 +124,11 @@ type FieldError struct {
 
 // FieldErrors represents a collection of field errors.
 type FieldErrors struct {
-	Code     ErrCode      `json:"code"`
-	Messages []FieldError `json:"messages"`
-	FuncName string       `json:"-"`
-	FileName string       `json:"-"`
+	Code      ErrCode      `json:"code"`
+	Messages  []FieldError `json:"messages"`
+	RequestID string       `json:"request_id,omitempty"`
+	FuncName  string       `json:"-"`
+	FileName  string       `json:"-"`
 }
 
 // NewFieldErrors creates a field errors.
@@ -2,11 +2,14 @@ package mid
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/nutchapon-m/web-server/app/sdk/authclient"
 	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/jwt"
 	"github.com/nutchapon-m/web-server/foundation/web"
 )
 
@@ -33,3 +36,50 @@ func Authenticate(client *authclient.Client) web.MidFunc {
 
 	return m
 }
+
+// AuthenticateJWT verifies the bearer token of the request locally, without
+// a round trip to the auth service, and attaches its claims and subject to
+// the request context.
+func AuthenticateJWT(verifier *jwt.Verifier) web.MidFunc {
+	m := func(next web.HandlerFunc) web.HandlerFunc {
+		h := func(ctx context.Context, r *http.Request) web.Encoder {
+			scheme, token, ok := strings.Cut(r.Header.Get("authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				return errs.Newf(errs.Unauthenticated, "expected authorization header format: Bearer <token>")
+			}
+
+			claims, err := verifier.Verify(ctx, token)
+			if err != nil {
+				if isTokenError(err) {
+					return errs.New(errs.Unauthenticated, err)
+				}
+				return errs.New(errs.Unavailable, err)
+			}
+
+			ctx = setClaims(ctx, claims)
+			ctx = setUserID(ctx, claims.Subject)
+
+			return next(ctx, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// isTokenError reports whether the token was rejected, as opposed to the
+// keys being unavailable.
+func isTokenError(err error) bool {
+	for _, target := range []error{
+		jwt.ErrTokenMalformed, jwt.ErrAlgorithm, jwt.ErrUnknownKey, jwt.ErrSignatureInvalid,
+		jwt.ErrTokenExpired, jwt.ErrTokenNotYetValid, jwt.ErrTokenUsedTooEarly,
+		jwt.ErrInvalidIssuer, jwt.ErrInvalidAudience,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
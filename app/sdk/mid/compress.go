@@ -0,0 +1,387 @@
+package mid
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// Compressor describes a content encoding that can be negotiated with the
+// Accept-Encoding header. Encodings like brotli can be plugged in by
+// providing their writer constructor.
+type Compressor struct {
+	Encoding string
+	New      func(w io.Writer) io.WriteCloser
+}
+
+// Gzip returns a gzip compressor for the level.
+func Gzip(level int) Compressor {
+	return Compressor{
+		Encoding: "gzip",
+		New: func(w io.Writer) io.WriteCloser {
+			gw, err := gzip.NewWriterLevel(w, level)
+			if err != nil {
+				return gzip.NewWriter(w)
+			}
+			return gw
+		},
+	}
+}
+
+// Deflate returns a deflate compressor for the level.
+func Deflate(level int) Compressor {
+	return Compressor{
+		Encoding: "deflate",
+		New: func(w io.Writer) io.WriteCloser {
+			fw, err := flate.NewWriter(w, level)
+			if err != nil {
+				fw, _ = flate.NewWriter(w, flate.DefaultCompression)
+			}
+			return fw
+		},
+	}
+}
+
+// CompressConfig defines how responses are compressed.
+type CompressConfig struct {
+	// MinSize is the smallest encoded response that is compressed. Streamed
+	// responses are always compressed when their content type allows it.
+	MinSize int
+
+	// Compressors lists the supported encodings in order of preference.
+	Compressors []Compressor
+
+	// SkipTypes lists content type prefixes that are already compressed.
+	SkipTypes []string
+}
+
+// DefaultCompressConfig returns the configuration used by the services.
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		MinSize:     1024,
+		Compressors: []Compressor{Gzip(gzip.DefaultCompression), Deflate(flate.DefaultCompression)},
+		SkipTypes: []string{
+			"image/",
+			"video/",
+			"audio/",
+			"font/woff",
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/zstd",
+			"application/octet-stream",
+			"application/pdf",
+		},
+	}
+}
+
+// Compress compresses responses with the encoding negotiated through the
+// Accept-Encoding header. Streamed responses written through web.GetWriter
+// are compressed as they are written and flushed with the writer.
+func Compress(cfg CompressConfig) web.MidFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			w := web.GetWriter(ctx)
+			if w == nil {
+				return next(ctx, r)
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			c, ok := cfg.negotiate(r.Header.Get("Accept-Encoding"))
+			if !ok || r.Method == http.MethodHead {
+				return next(ctx, r)
+			}
+
+			cw := compressWriter{ResponseWriter: w, cfg: cfg, c: c}
+			defer cw.Close()
+
+			resp := next(web.SetWriter(ctx, &cw), r)
+
+			if cw.started {
+				return resp
+			}
+
+			switch v := resp.(type) {
+			case nil, web.NoResponse, error:
+				return resp
+			case web.Streamer:
+				return compressStreamer{Streamer: v, cfg: cfg, c: c}
+			}
+
+			return compressEncoder{Encoder: resp, w: w, cfg: cfg, c: c}
+		}
+	}
+}
+
+// negotiate returns the preferred compressor accepted by the client.
+func (cfg CompressConfig) negotiate(acceptEncoding string) (Compressor, bool) {
+	if acceptEncoding == "" {
+		return Compressor{}, false
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	var best Compressor
+	var bestQ float64
+	for _, c := range cfg.Compressors {
+		q, ok := accepted[c.Encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+// compressible reports whether the content type should be compressed.
+func (cfg CompressConfig) compressible(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return h.Get("Content-Type") == ""
+	}
+
+	for _, skip := range cfg.SkipTypes {
+		if strings.HasPrefix(mediaType, skip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// weakenETag marks a strong ETag as weak since the compressed body is no
+// longer byte for byte the representation it was computed for.
+func weakenETag(h http.Header) {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+// =============================================================================
+
+// compressEncoder compresses the payload of a buffered response.
+type compressEncoder struct {
+	web.Encoder
+	w   http.ResponseWriter
+	cfg CompressConfig
+	c   Compressor
+}
+
+func (ce compressEncoder) HTTPStatus() int {
+	if v, ok := ce.Encoder.(interface{ HTTPStatus() int }); ok {
+		return v.HTTPStatus()
+	}
+
+	return http.StatusOK
+}
+
+func (ce compressEncoder) Encode() ([]byte, string, error) {
+	data, contentType, err := ce.Encoder.Encode()
+	if err != nil || len(data) < ce.cfg.MinSize {
+		return data, contentType, err
+	}
+
+	h := ce.w.Header()
+	h.Set("Content-Type", contentType)
+	if !ce.cfg.compressible(h) {
+		return data, contentType, nil
+	}
+
+	var b bytes.Buffer
+	zw := ce.c.New(&b)
+	if _, err := zw.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	h.Set("Content-Encoding", ce.c.Encoding)
+	h.Del("Content-Length")
+	weakenETag(h)
+
+	return b.Bytes(), contentType, nil
+}
+
+// =============================================================================
+
+// compressStreamer compresses a streamed response as it is written.
+type compressStreamer struct {
+	web.Streamer
+	cfg CompressConfig
+	c   Compressor
+}
+
+func (cs compressStreamer) HTTPStatus() int {
+	if v, ok := cs.Streamer.(interface{ HTTPStatus() int }); ok {
+		return v.HTTPStatus()
+	}
+
+	return http.StatusOK
+}
+
+func (cs compressStreamer) Stream(ctx context.Context, w http.ResponseWriter) error {
+	cw := compressWriter{ResponseWriter: w, cfg: cs.cfg, c: cs.c}
+	defer cw.Close()
+
+	return cs.Streamer.Stream(ctx, &cw)
+}
+
+// =============================================================================
+
+// compressWriter compresses a streamed response. The decision to compress is
+// made on the first write once the handler has set the content type. When
+// the handler hasn't set one, it's sniffed from the uncompressed data of the
+// first write, as net/http would do, and the status is held until then.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      CompressConfig
+	c        Compressor
+	zw       io.WriteCloser
+	status   int
+	started  bool
+	decided  bool
+	compress bool
+}
+
+func (cw *compressWriter) decide() {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	h := cw.ResponseWriter.Header()
+	if !cw.cfg.compressible(h) {
+		return
+	}
+
+	h.Set("Content-Encoding", cw.c.Encoding)
+	h.Del("Content-Length")
+	weakenETag(h)
+	cw.compress = true
+}
+
+// commit decides whether to compress and writes the held status, if any.
+func (cw *compressWriter) commit() {
+	cw.decide()
+
+	if cw.status != 0 {
+		statusCode := cw.status
+		cw.status = 0
+		cw.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if statusCode < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	cw.started = true
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	// The content type is sniffed from the first write.
+	if !cw.decided && cw.ResponseWriter.Header().Get("Content-Type") == "" {
+		cw.status = statusCode
+		return
+	}
+
+	cw.decide()
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	cw.started = true
+
+	if !cw.decided {
+		if len(b) == 0 {
+			return 0, nil
+		}
+
+		h := cw.ResponseWriter.Header()
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(b))
+		}
+	}
+	cw.commit()
+
+	if !cw.compress {
+		return cw.ResponseWriter.Write(b)
+	}
+
+	if cw.zw == nil {
+		cw.zw = cw.c.New(cw.ResponseWriter)
+	}
+
+	return cw.zw.Write(b)
+}
+
+// Flush flushes the compressor and the underlying writer so streamed events
+// reach the client.
+func (cw *compressWriter) Flush() {
+	// Flushing sends the headers, so the encoding must be decided first.
+	cw.started = true
+	cw.commit()
+
+	if f, ok := cw.zw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap supports http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the compressed stream.
+func (cw *compressWriter) Close() error {
+	if cw.started {
+		cw.commit()
+	}
+
+	// The headers announce the encoding, so even an empty body must be a
+	// valid compressed stream.
+	if cw.compress && cw.zw == nil {
+		cw.zw = cw.c.New(cw.ResponseWriter)
+	}
+
+	if cw.zw == nil {
+		return nil
+	}
+
+	return cw.zw.Close()
+}
@@ -0,0 +1,110 @@
+package mid
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+func TestCompressStream(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 64)
+	text := strings.Repeat("hello world ", 10)
+
+	tests := []struct {
+		name     string
+		write    func(w http.ResponseWriter)
+		encoding string
+		etag     string
+		body     string
+	}{
+		{
+			name:     "sniffed text",
+			write:    func(w http.ResponseWriter) { io.WriteString(w, text) },
+			encoding: "gzip",
+			body:     text,
+		},
+		{
+			name:  "sniffed image",
+			write: func(w http.ResponseWriter) { io.WriteString(w, png) },
+			body:  png,
+		},
+		{
+			name: "sniffed after status",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, png)
+			},
+			body: png,
+		},
+		{
+			name: "flush before write",
+			write: func(w http.ResponseWriter) {
+				w.(http.Flusher).Flush()
+				io.WriteString(w, text)
+			},
+			encoding: "gzip",
+			body:     text,
+		},
+		{
+			name: "strong etag",
+			write: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("ETag", `"v1"`)
+				io.WriteString(w, text)
+			},
+			encoding: "gzip",
+			etag:     `W/"v1"`,
+			body:     text,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CompressConfig{
+				Compressors: []Compressor{Gzip(gzip.DefaultCompression)},
+				SkipTypes:   []string{"image/"},
+			}
+
+			handler := Compress(cfg)(func(ctx context.Context, r *http.Request) web.Encoder {
+				tt.write(web.GetWriter(ctx))
+				return web.NewNoResponse()
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+
+			handler(web.SetWriter(context.Background(), w), r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("got encoding %q, want %q", got, tt.encoding)
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("got etag %q, want %q", got, tt.etag)
+			}
+
+			var body io.Reader = w.Body
+			if tt.encoding == "gzip" {
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip: %s", err)
+				}
+				body = zr
+			}
+
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("read: %s", err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("got body %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
@@ -2,38 +2,48 @@ package mid
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
-	"slices"
 
 	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/csrf"
 	"github.com/nutchapon-m/web-server/foundation/web"
 )
 
-var (
-	csrfTokenKey    = "X-CSRF-Token"
-	csrfAllowMethod = []string{"GET", "HEAD", "OPTIONS", "TRACE"}
-
-	storer map[string]string // build-in memory cache in future.
-)
-
-func CSRF() web.MidFunc {
+// CSRF verifies the token of requests with unsafe methods. Requests with
+// safe methods receive a token when they don't have a valid one, so pages
+// and single page applications always have a token to submit.
+func CSRF(p *csrf.Protector) web.MidFunc {
 	return func(next web.HandlerFunc) web.HandlerFunc {
 		return func(ctx context.Context, r *http.Request) web.Encoder {
-
-			if slices.Contains(csrfAllowMethod, r.Method) {
+			if p.Exempt(r) {
 				return next(ctx, r)
 			}
 
-			val := r.Header.Get(csrfTokenKey)
+			w := web.GetWriter(ctx)
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				if _, err := p.Token(ctx, w, r); err != nil {
+					return errs.New(errs.Internal, err)
+				}
+				return next(ctx, r)
+			}
 
-			if val == "" {
-				return errs.Newf(errs.PermissionDenied, "The csrf token is required")
+			rotate, err := p.Verify(ctx, r)
+			if err != nil {
+				switch {
+				case errors.Is(err, csrf.ErrTokenMissing), errors.Is(err, csrf.ErrTokenInvalid),
+					errors.Is(err, csrf.ErrTokenExpired), errors.Is(err, csrf.ErrTokenMismatch):
+					return errs.New(errs.PermissionDenied, err)
+				}
+				return errs.New(errs.Internal, err)
 			}
 
-			key := fmt.Sprintf("ua:%s:csrftoken:%s", r.UserAgent(), val)
-			if _, exsits := storer[key]; !exsits {
-				return errs.Newf(errs.PermissionDenied, "Invalid csrf token")
+			if rotate {
+				if _, err := p.Rotate(ctx, w, r); err != nil {
+					return errs.New(errs.Internal, err)
+				}
 			}
 
 			return next(ctx, r)
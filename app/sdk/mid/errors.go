@@ -2,6 +2,7 @@ package mid
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"path"
 
@@ -20,6 +21,11 @@ func Errors(log *logger.Logger) web.MidFunc {
 				return resp
 			}
 
+			var webErr *web.Error
+			if errors.As(err, &webErr) {
+				err = appError(webErr)
+			}
+
 			switch e := err.(type) {
 			case *errs.Error:
 				log.Error(ctx, "handled error during request",
@@ -29,16 +35,62 @@ func Errors(log *logger.Logger) web.MidFunc {
 				if e.Code == errs.InternalOnlyLog {
 					e = errs.Newf(errs.Internal, "Internal Server Error")
 				}
-				return e
+
+				// Handlers can return a shared error value, so the request
+				// ID is set on a copy.
+				resp := *e
+				resp.RequestID = web.RequestID(ctx)
+				return &resp
 			case *errs.FieldErrors:
 				log.Error(ctx, "handled error during request",
 					"err", err,
 					"source_err_file", path.Base(e.FileName),
 					"source_err_func", path.Base(e.FuncName))
-				return e
+				resp := *e
+				resp.RequestID = web.RequestID(ctx)
+				return &resp
 			default:
-				return errs.Newf(errs.Internal, "Internal Server Error")
+				internal := errs.Newf(errs.Internal, "Internal Server Error")
+				internal.RequestID = web.RequestID(ctx)
+				return internal
 			}
 		}
 	}
 }
+
+// appError converts an error of the web package into the errs type with the
+// code matching its status. The message of server errors is only logged.
+func appError(e *web.Error) error {
+	if len(e.Fields) > 0 {
+		fieldErrs := errs.NewFieldErrors(errs.InvalidArgument)
+		for _, f := range e.Fields {
+			fieldErrs.Messages = append(fieldErrs.Messages, errs.FieldError{Field: f.Field, Err: f.Err})
+		}
+		return fieldErrs
+	}
+
+	return errs.Newf(statusCode(e.Status), "%s", e.Message)
+}
+
+// statusCode returns the error code for the statuses used by the web
+// package.
+func statusCode(status int) errs.ErrCode {
+	switch status {
+	case http.StatusBadRequest:
+		return errs.InvalidArgument
+	case http.StatusForbidden:
+		return errs.PermissionDenied
+	case http.StatusNotFound:
+		return errs.NotFound
+	case http.StatusMethodNotAllowed:
+		return errs.MethodNotAllowed
+	case http.StatusNotAcceptable:
+		return errs.NotAcceptable
+	case http.StatusRequestEntityTooLarge:
+		return errs.PayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return errs.UnsupportedMediaType
+	}
+
+	return errs.InternalOnlyLog
+}
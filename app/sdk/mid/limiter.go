@@ -2,23 +2,172 @@ package mid
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/ratelimit"
 	"github.com/nutchapon-m/web-server/foundation/web"
-	"golang.org/x/time/rate"
 )
 
-var limiter = rate.NewLimiter(rate.Every(10*time.Second), 5)
+// KeyFunc returns the key requests are counted under. An empty key falls
+// back to the IP of the client.
+type KeyFunc func(ctx context.Context, r *http.Request) string
+
+// KeyByIP counts the requests by the IP of the client. Behind a proxy the
+// remote address is the proxy, so provide a KeyFunc reading the header the
+// trusted proxy sets instead.
+func KeyByIP() KeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		return "ip:" + clientIP(r)
+	}
+}
+
+// KeyByUserID counts the requests by the authenticated user. It must run
+// after Authenticate.
+func KeyByUserID() KeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		userID, err := GetUserID(ctx)
+		if err != nil {
+			return ""
+		}
+		return "user:" + userID
+	}
+}
+
+// KeyByAPIKey counts the requests by the API key found in the header. The
+// key is hashed so API keys are never written to the store.
+func KeyByAPIKey(header string) KeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		key := r.Header.Get(header)
+		if key == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(key))
+		return "apikey:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// LimiterConfig configures the Limiter middleware.
+type LimiterConfig struct {
+	// Store keeps the state of the keys. It defaults to a memory store.
+	Store ratelimit.Store
+
+	// Quota applies to every route without its own quota.
+	Quota ratelimit.Quota
+
+	// Routes overrides the quota by route pattern, like "GET /api/login" or
+	// "/api/search" for every method. Each route is counted separately.
+	Routes map[string]ratelimit.Quota
+
+	// Key returns the key of the client. It defaults to KeyByIP.
+	Key KeyFunc
+
+	// FailClosed rejects requests with a 503 when the store fails. By
+	// default requests are allowed so an outage of the store doesn't become
+	// an outage of the service.
+	FailClosed bool
+}
+
+// DefaultLimiterConfig allows 100 requests per minute by client IP.
+func DefaultLimiterConfig() LimiterConfig {
+	return LimiterConfig{
+		Store: ratelimit.NewMemoryStore(),
+		Quota: ratelimit.PerMinute(100),
+		Key:   KeyByIP(),
+	}
+}
+
+// Limiter rejects the requests of a client exceeding its quota with a 429
+// and a Retry-After header. The RateLimit headers tell clients their quota
+// on every response. Store failures are logged and the request is allowed
+// or rejected based on FailClosed.
+func Limiter(log *logger.Logger, cfg LimiterConfig) web.MidFunc {
+	if cfg.Store == nil {
+		cfg.Store = ratelimit.NewMemoryStore()
+	}
+
+	if cfg.Key == nil {
+		cfg.Key = KeyByIP()
+	}
 
-func Limiter() web.MidFunc {
 	return func(next web.HandlerFunc) web.HandlerFunc {
 		return func(ctx context.Context, r *http.Request) web.Encoder {
-			if err := limiter.Wait(ctx); err != nil {
-				return errs.Newf(errs.TooManyRequests, "Rate limit exceeded")
+			key := cfg.Key(ctx, r)
+			if key == "" {
+				key = "ip:" + clientIP(r)
 			}
+
+			quota := cfg.Quota
+			if routeQuota, route, ok := cfg.routeQuota(r); ok {
+				quota = routeQuota
+				key = key + "|" + route
+			}
+
+			res, err := cfg.Store.Take(ctx, key, quota)
+			if err != nil {
+				log.Error(ctx, "limiter: store", "err", err, "fail_closed", cfg.FailClosed)
+				if cfg.FailClosed {
+					return errs.Newf(errs.Unavailable, "Rate limiter unavailable")
+				}
+				return next(ctx, r)
+			}
+
+			if w := web.GetWriter(ctx); w != nil {
+				h := w.Header()
+				h.Set("RateLimit-Policy", ratelimit.Policy(res.Limit, quota.Period))
+				h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+				h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				h.Set("RateLimit-Reset", ceilSeconds(res.ResetAfter))
+
+				if !res.Allowed {
+					h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				}
+			}
+
+			if !res.Allowed {
+				return errs.Newf(errs.TooManyRequests, "Rate limit exceeded, retry in %s seconds", ceilSeconds(res.RetryAfter))
+			}
+
 			return next(ctx, r)
 		}
 	}
 }
+
+// routeQuota returns the quota of the matched route, looking for the
+// pattern with its method first.
+func (cfg LimiterConfig) routeQuota(r *http.Request) (ratelimit.Quota, string, bool) {
+	if len(cfg.Routes) == 0 || r.Pattern == "" {
+		return ratelimit.Quota{}, "", false
+	}
+
+	if quota, exists := cfg.Routes[r.Pattern]; exists {
+		return quota, r.Pattern, true
+	}
+
+	route := routeLabel(r)
+	if quota, exists := cfg.Routes[route]; exists {
+		return quota, route, true
+	}
+
+	return ratelimit.Quota{}, "", false
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats the duration in whole seconds, rounding up so clients
+// never retry too early.
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
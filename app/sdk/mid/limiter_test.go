@@ -0,0 +1,140 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/ratelimit"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+type failStore struct{}
+
+func (failStore) Take(ctx context.Context, key string, quota ratelimit.Quota) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store down")
+}
+
+func TestLimiter(t *testing.T) {
+	type request struct {
+		pattern string
+		user    string
+		allowed bool
+		code    errs.ErrCode
+		headers map[string]string
+	}
+
+	byUser := func(ctx context.Context, r *http.Request) string {
+		return r.Header.Get("X-User")
+	}
+
+	tests := []struct {
+		name     string
+		cfg      LimiterConfig
+		requests []request
+	}{
+		{
+			name: "burst",
+			cfg:  LimiterConfig{Quota: ratelimit.Quota{Limit: 1, Period: time.Minute, Burst: 3}},
+			requests: []request{
+				{allowed: true, headers: map[string]string{"RateLimit-Policy": "3;w=60", "RateLimit-Limit": "3", "RateLimit-Remaining": "2"}},
+				{allowed: true, headers: map[string]string{"RateLimit-Remaining": "1"}},
+				{allowed: true, headers: map[string]string{"RateLimit-Remaining": "0"}},
+				{
+					code:    errs.TooManyRequests,
+					headers: map[string]string{"RateLimit-Policy": "3;w=60", "RateLimit-Limit": "3", "RateLimit-Remaining": "0", "Retry-After": "60"},
+				},
+			},
+		},
+		{
+			name: "route quota",
+			cfg: LimiterConfig{
+				Quota:  ratelimit.PerMinute(100),
+				Routes: map[string]ratelimit.Quota{"POST /login": ratelimit.PerMinute(1)},
+			},
+			requests: []request{
+				{pattern: "POST /login", allowed: true, headers: map[string]string{"RateLimit-Limit": "1", "RateLimit-Remaining": "0"}},
+				{pattern: "POST /login", code: errs.TooManyRequests},
+				{pattern: "GET /home", allowed: true, headers: map[string]string{"RateLimit-Policy": "100;w=60", "RateLimit-Remaining": "99"}},
+			},
+		},
+		{
+			name: "key",
+			cfg:  LimiterConfig{Quota: ratelimit.PerMinute(1), Key: byUser},
+			requests: []request{
+				{user: "a", allowed: true},
+				{user: "b", allowed: true},
+				{user: "a", code: errs.TooManyRequests},
+				{allowed: true},
+			},
+		},
+		{
+			name: "fail open",
+			cfg:  LimiterConfig{Store: failStore{}, Quota: ratelimit.PerMinute(1)},
+			requests: []request{
+				{allowed: true},
+			},
+		},
+		{
+			name: "fail closed",
+			cfg:  LimiterConfig{Store: failStore{}, Quota: ratelimit.PerMinute(1), FailClosed: true},
+			requests: []request{
+				{code: errs.Unavailable},
+			},
+		},
+	}
+
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.Store == nil {
+				now := time.Unix(0, 0)
+				tt.cfg.Store = ratelimit.NewMemoryStore(ratelimit.WithClock(func() time.Time { return now }))
+			}
+
+			var called bool
+			handler := Limiter(log, tt.cfg)(func(ctx context.Context, r *http.Request) web.Encoder {
+				called = true
+				return web.NewNoResponse()
+			})
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Pattern = req.pattern
+				if req.user != "" {
+					r.Header.Set("X-User", req.user)
+				}
+				w := httptest.NewRecorder()
+
+				called = false
+				resp := handler(web.SetWriter(context.Background(), w), r)
+
+				if called != req.allowed {
+					t.Errorf("request %d: got allowed %t, want %t", i, called, req.allowed)
+				}
+
+				if !req.allowed {
+					err, _ := resp.(error)
+
+					var appErr *errs.Error
+					if !errors.As(err, &appErr) || appErr.Code != req.code {
+						t.Errorf("request %d: got %v, want code %s", i, resp, req.code)
+					}
+				}
+
+				for k, want := range req.headers {
+					if got := w.Header().Get(k); got != want {
+						t.Errorf("request %d: got %s %q, want %q", i, k, got, want)
+					}
+				}
+			}
+		})
+	}
+}
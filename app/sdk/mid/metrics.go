@@ -0,0 +1,91 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/metrics"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// Metrics records the number, latency and errors of the requests by route
+// pattern so the cardinality doesn't grow with the paths requested.
+//
+// The status and the error code are taken from the encoder returned by the
+// handlers, not from the writer, so it must run outside of Errors to count
+// errors with the code they're mapped to. The latency is measured until the
+// handlers return: it excludes encoding and writing the body of buffered
+// responses, and streamed responses like File are recorded before their
+// body is written. Responses the handlers write themselves, like SSE, are
+// counted with the 200 status and their latency includes the writing.
+func Metrics(reg *metrics.Registry) web.MidFunc {
+	requests := reg.NewCounter("http_requests_total", "Number of handled requests.", "method", "route", "status")
+	failures := reg.NewCounter("http_request_errors_total", "Number of requests that failed by error code.", "method", "route", "code")
+	latency := reg.NewHistogram("http_request_duration_seconds", "Latency of the requests in seconds.", metrics.DefaultBuckets, "method", "route")
+	inFlight := reg.NewGauge("http_requests_in_flight", "Number of requests being handled.")
+
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			now := time.Now()
+
+			resp := next(ctx, r)
+
+			route := routeLabel(r)
+			latency.Observe(time.Since(now).Seconds(), r.Method, route)
+			requests.Inc(r.Method, route, strconv.Itoa(responseStatus(resp)))
+
+			if err := isError(resp); err != nil {
+				code := errs.Internal
+
+				var appErr *errs.Error
+				var fieldErr *errs.FieldErrors
+				switch {
+				case errors.As(err, &appErr):
+					code = appErr.Code
+				case errors.As(err, &fieldErr):
+					code = fieldErr.Code
+				}
+
+				failures.Inc(r.Method, route, code.String())
+			}
+
+			return resp
+		}
+	}
+}
+
+// routeLabel returns the path of the matched route, or a fixed value for
+// requests that didn't match any route.
+func routeLabel(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+
+	return r.Pattern
+}
+
+// responseStatus returns the HTTP status the response will be sent with.
+func responseStatus(resp web.Encoder) int {
+	switch v := resp.(type) {
+	case interface{ HTTPStatus() int }:
+		return v.HTTPStatus()
+	case error:
+		return http.StatusInternalServerError
+	case nil:
+		return http.StatusNoContent
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,119 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/metrics"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+func TestMetrics(t *testing.T) {
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST")
+
+	tests := []struct {
+		name    string
+		pattern string
+		handler web.HandlerFunc
+		errors  bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "status",
+			pattern: "POST /users/{id}",
+			handler: func(ctx context.Context, r *http.Request) web.Encoder {
+				return web.JSON(http.StatusCreated, "ok")
+			},
+			want: []string{
+				`http_requests_total{method="POST",route="/users/{id}",status="201"} 1`,
+				`http_request_duration_seconds_count{method="POST",route="/users/{id}"} 1`,
+				`http_requests_in_flight 0`,
+			},
+			notWant: []string{"http_request_errors_total{"},
+		},
+		{
+			name: "unmatched",
+			handler: func(ctx context.Context, r *http.Request) web.Encoder {
+				return nil
+			},
+			want: []string{
+				`http_requests_total{method="POST",route="unmatched",status="204"} 1`,
+			},
+		},
+		{
+			name:    "error code",
+			pattern: "POST /users/{id}",
+			handler: func(ctx context.Context, r *http.Request) web.Encoder {
+				return errs.Newf(errs.NotFound, "user not found")
+			},
+			want: []string{
+				`http_requests_total{method="POST",route="/users/{id}",status="404"} 1`,
+				`http_request_errors_total{method="POST",route="/users/{id}",code="not_found"} 1`,
+			},
+		},
+		{
+			name:    "mapped by errors",
+			pattern: "POST /users/{id}",
+			handler: func(ctx context.Context, r *http.Request) web.Encoder {
+				return web.NewError(http.StatusBadRequest, "bad input")
+			},
+			errors: true,
+			want: []string{
+				`http_requests_total{method="POST",route="/users/{id}",status="400"} 1`,
+				`http_request_errors_total{method="POST",route="/users/{id}",code="invalid_argument"} 1`,
+			},
+		},
+		{
+			name:    "written by the handler",
+			pattern: "POST /events",
+			handler: func(ctx context.Context, r *http.Request) web.Encoder {
+				return web.NewNoResponse()
+			},
+			want: []string{
+				`http_requests_total{method="POST",route="/events",status="200"} 1`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := metrics.NewRegistry()
+
+			handler := tt.handler
+			if tt.errors {
+				handler = Errors(log)(handler)
+			}
+			handler = Metrics(reg)(handler)
+
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Pattern = tt.pattern
+
+			handler(web.SetWriter(context.Background(), httptest.NewRecorder()), r)
+
+			var b bytes.Buffer
+			if _, err := reg.WriteTo(&b); err != nil {
+				t.Fatalf("write: %s", err)
+			}
+			got := b.String()
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want+"\n") {
+					t.Errorf("got\n%s\nwant it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("got\n%s\nwant it not to contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
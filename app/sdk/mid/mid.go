@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/jwt"
 	"github.com/nutchapon-m/web-server/foundation/web"
 )
 
@@ -42,3 +43,27 @@ const (
 func setUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
+
+func setClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	return context.WithValue(ctx, claimKey, claims)
+}
+
+// GetClaims returns the claims of the token verified by AuthenticateJWT.
+func GetClaims(ctx context.Context) (jwt.Claims, error) {
+	v, ok := ctx.Value(claimKey).(jwt.Claims)
+	if !ok {
+		return jwt.Claims{}, errors.New("claims not found in context")
+	}
+
+	return v, nil
+}
+
+// GetUserID returns the ID of the authenticated user from the context.
+func GetUserID(ctx context.Context) (string, error) {
+	v, ok := ctx.Value(userIDKey).(string)
+	if !ok || v == "" {
+		return "", errors.New("user id not found in context")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,64 @@
+package mid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// maxRequestIDLen limits the size of a request ID accepted from a client.
+const maxRequestIDLen = 128
+
+// RequestID accepts the X-Request-ID of the caller or generates a new one,
+// stores it in the context and echoes it in the response so logs, errors
+// and outbound calls of the same request can be correlated.
+func RequestID() web.MidFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			id := r.Header.Get(web.RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			ctx = web.SetRequestID(ctx, id)
+
+			if w := web.GetWriter(ctx); w != nil {
+				w.Header().Set(web.RequestIDHeader, id)
+			}
+
+			return next(ctx, r)
+		}
+	}
+}
+
+// validRequestID only accepts IDs that are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
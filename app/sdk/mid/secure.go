@@ -0,0 +1,127 @@
+package mid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// NoncePlaceholder is replaced in the Content-Security-Policy with a nonce
+// generated for each request.
+const NoncePlaceholder = "{nonce}"
+
+// SecurePolicy defines the security headers set on every response. Empty
+// values are not sent.
+type SecurePolicy struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubDomains bool
+	HSTSPreload           bool
+	ContentSecurityPolicy string
+	ContentTypeNosniff    bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+}
+
+// DefaultSecurePolicy returns the policy for the build mode. HSTS is only
+// sent outside of develop mode since develop runs over plain http.
+func DefaultSecurePolicy(build string) SecurePolicy {
+	policy := SecurePolicy{
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; " +
+			"object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		ContentTypeNosniff: true,
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+		PermissionsPolicy:  "camera=(), microphone=(), geolocation=()",
+	}
+
+	if build != "develop" {
+		policy.HSTSMaxAge = 2 * 365 * 24 * time.Hour
+		policy.HSTSIncludeSubDomains = true
+		policy.HSTSPreload = true
+	}
+
+	return policy
+}
+
+// hsts returns the value of the Strict-Transport-Security header.
+func (p SecurePolicy) hsts() string {
+	if p.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	v := fmt.Sprintf("max-age=%d", int(p.HSTSMaxAge.Seconds()))
+	if p.HSTSIncludeSubDomains {
+		v += "; includeSubDomains"
+	}
+	if p.HSTSPreload {
+		v += "; preload"
+	}
+
+	return v
+}
+
+// SecureHeaders sets the security headers of the policy on the response. When
+// the Content-Security-Policy contains the nonce placeholder a nonce is
+// generated and stored in the context, see web.Nonce.
+func SecureHeaders(policy SecurePolicy) web.MidFunc {
+	hsts := policy.hsts()
+	withNonce := strings.Contains(policy.ContentSecurityPolicy, NoncePlaceholder)
+
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			w := web.GetWriter(ctx)
+			if w == nil {
+				return next(ctx, r)
+			}
+			h := w.Header()
+
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			if csp := policy.ContentSecurityPolicy; csp != "" {
+				if withNonce {
+					nonce, err := newNonce()
+					if err != nil {
+						return errs.Newf(errs.Internal, "generate nonce: %s", err)
+					}
+					ctx = web.SetNonce(ctx, nonce)
+					csp = strings.ReplaceAll(csp, NoncePlaceholder, nonce)
+				}
+				h.Set("Content-Security-Policy", csp)
+			}
+
+			if policy.ContentTypeNosniff {
+				h.Set("X-Content-Type-Options", "nosniff")
+			}
+			if policy.FrameOptions != "" {
+				h.Set("X-Frame-Options", policy.FrameOptions)
+			}
+			if policy.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", policy.ReferrerPolicy)
+			}
+			if policy.PermissionsPolicy != "" {
+				h.Set("Permissions-Policy", policy.PermissionsPolicy)
+			}
+
+			return next(ctx, r)
+		}
+	}
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
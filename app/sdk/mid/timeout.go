@@ -0,0 +1,308 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// Timeout derives a context deadline for the rest of the call chain. When the
+// deadline passes before the handler returns, an errs.DeadlineExceeded error
+// is returned and anything the handler writes afterwards is discarded. When
+// used on a route inside an app level Timeout, the shorter deadline wins.
+//
+// Responses that implement web.Streamer are written after the handler
+// returns, so the deadline is carried over to the call to Stream. Long lived
+// routes, like WebSocket and event stream routes, opt out with NoTimeout.
+func Timeout(d time.Duration) web.MidFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			ctl := timeoutControl{
+				parent:   ctx,
+				outer:    getTimeoutControl(ctx),
+				detached: make(chan struct{}),
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			ctx = context.WithValue(ctx, timeoutKey, &ctl)
+
+			w := web.GetWriter(ctx)
+			if w == nil {
+				return next(ctx, r)
+			}
+
+			tw := timeoutWriter{
+				w: w,
+				h: w.Header().Clone(),
+			}
+
+			done := make(chan web.Encoder, 1)
+			panics := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						panics <- rec
+					}
+				}()
+
+				done <- next(web.SetWriter(ctx, &tw), r.WithContext(ctx))
+			}()
+
+			select {
+			case resp := <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				if !tw.wroteHeader {
+					tw.copyHeader()
+				}
+
+				if s, ok := resp.(web.Streamer); ok {
+					deadline, _ := ctx.Deadline()
+					return timeoutStreamer{Streamer: s, deadline: deadline}
+				}
+				return resp
+
+			case rec := <-panics:
+				// Panic again on this goroutine so the Panics middleware
+				// recovers it.
+				panic(rec)
+
+			case <-ctl.detached:
+				select {
+				case resp := <-done:
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+
+					if !tw.wroteHeader {
+						tw.copyHeader()
+					}
+					return resp
+
+				case rec := <-panics:
+					panic(rec)
+				}
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				if tw.wroteHeader {
+					return web.NewNoResponse()
+				}
+				return errs.Newf(errs.DeadlineExceeded, "request exceeded the timeout of %s", d)
+			}
+		}
+	}
+}
+
+// NoTimeout removes the deadlines set by Timeout for the rest of the call
+// chain. It's meant for the routes of long lived responses, like WebSocket
+// upgrades and event streams, which must opt out explicitly:
+//
+//	app.HandlerFunc(http.MethodGet, "v1", "/events", h.events, mid.NoTimeout())
+//
+// The request is still canceled when the client goes away.
+func NoTimeout() web.MidFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx context.Context, r *http.Request) web.Encoder {
+			ctl := getTimeoutControl(ctx)
+			if ctl == nil {
+				return next(ctx, r)
+			}
+
+			var parent context.Context
+			for ; ctl != nil; ctl = ctl.outer {
+				ctl.detach()
+				parent = ctl.parent
+			}
+
+			ctx = detachedContext{Context: ctx, parent: parent}
+			return next(ctx, r.WithContext(ctx))
+		}
+	}
+}
+
+type timeoutCtxKey int
+
+const timeoutKey timeoutCtxKey = 1
+
+// timeoutControl lets NoTimeout tell a Timeout up the chain to stop waiting
+// for its deadline.
+type timeoutControl struct {
+	parent   context.Context
+	outer    *timeoutControl
+	detached chan struct{}
+	once     sync.Once
+}
+
+func (ctl *timeoutControl) detach() {
+	ctl.once.Do(func() {
+		close(ctl.detached)
+	})
+}
+
+func getTimeoutControl(ctx context.Context) *timeoutControl {
+	ctl, _ := ctx.Value(timeoutKey).(*timeoutControl)
+	return ctl
+}
+
+// detachedContext keeps the values of the request but takes its deadline
+// and cancellation from the context the first Timeout received.
+type detachedContext struct {
+	context.Context
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return c.parent.Deadline()
+}
+
+func (c detachedContext) Done() <-chan struct{} {
+	return c.parent.Done()
+}
+
+func (c detachedContext) Err() error {
+	return c.parent.Err()
+}
+
+// =============================================================================
+
+// timeoutStreamer writes a streamed response within the deadline of the
+// request.
+type timeoutStreamer struct {
+	web.Streamer
+	deadline time.Time
+}
+
+func (ts timeoutStreamer) HTTPStatus() int {
+	if v, ok := ts.Streamer.(interface{ HTTPStatus() int }); ok {
+		return v.HTTPStatus()
+	}
+
+	return http.StatusOK
+}
+
+func (ts timeoutStreamer) Stream(ctx context.Context, w http.ResponseWriter) error {
+	ctx, cancel := context.WithDeadline(ctx, ts.deadline)
+	defer cancel()
+
+	tw := timeoutWriter{
+		w: w,
+		h: w.Header().Clone(),
+	}
+
+	timer := time.AfterFunc(time.Until(ts.deadline), tw.timeout)
+	defer timer.Stop()
+
+	// The write deadline stops writes blocked on a slow client. It is
+	// cleared afterwards so it doesn't leak to the next request on the
+	// connection.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(ts.deadline); err == nil {
+		defer rc.SetWriteDeadline(time.Time{})
+	}
+
+	err := ts.Streamer.Stream(ctx, &tw)
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if !tw.wroteHeader && !tw.timedOut {
+		tw.copyHeader()
+	}
+
+	return err
+}
+
+// =============================================================================
+
+// timeoutWriter buffers the headers set by the handler and drops every write
+// once the timeout has fired.
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+// timeout drops every write made from now on.
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.timedOut = true
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// copyHeader replaces the headers of the response with the headers set by
+// the handler.
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.wroteHeader = true
+	tw.copyHeader()
+	tw.w.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.copyHeader()
+	}
+
+	return tw.w.Write(b)
+}
+
+// Flush sends buffered data to the client unless the timeout has fired.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap supports http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
@@ -0,0 +1,76 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+func TestTimeout(t *testing.T) {
+	slow := func(ctx context.Context, r *http.Request) web.Encoder {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return web.JSON(http.StatusOK, "done")
+		case <-ctx.Done():
+			return web.NewNoResponse()
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mw       []web.MidFunc
+		header   http.Header
+		timedOut bool
+	}{
+		{
+			name:     "slow",
+			mw:       []web.MidFunc{Timeout(10 * time.Millisecond)},
+			timedOut: true,
+		},
+		{
+			name:     "upgrade header",
+			mw:       []web.MidFunc{Timeout(10 * time.Millisecond)},
+			header:   http.Header{"Upgrade": {"websocket"}, "Accept": {"text/event-stream"}},
+			timedOut: true,
+		},
+		{
+			name: "no timeout",
+			mw:   []web.MidFunc{Timeout(10 * time.Millisecond), NoTimeout()},
+		},
+		{
+			name: "nested",
+			mw:   []web.MidFunc{Timeout(10 * time.Millisecond), Timeout(5 * time.Millisecond), NoTimeout()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := slow
+			for i := len(tt.mw) - 1; i >= 0; i-- {
+				handler = tt.mw[i](handler)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			resp := handler(web.SetWriter(context.Background(), w), r)
+
+			err, _ := resp.(error)
+
+			var appErr *errs.Error
+			timedOut := errors.As(err, &appErr) && appErr.Code == errs.DeadlineExceeded
+			if timedOut != tt.timedOut {
+				t.Errorf("got timed out %t, want %t: %v", timedOut, tt.timedOut, resp)
+			}
+		})
+	}
+}
@@ -1,52 +1,225 @@
 package mux
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/nutchapon-m/web-server/app/sdk/errs"
 	"github.com/nutchapon-m/web-server/app/sdk/mid"
+	"github.com/nutchapon-m/web-server/foundation/csrf"
 	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/metrics"
+	"github.com/nutchapon-m/web-server/foundation/tracer"
 	"github.com/nutchapon-m/web-server/foundation/web"
 )
 
 // Options represent optional parameters.
 type Options struct {
-	corsOrigin []string
+	cors             *web.CORSPolicy
+	secure           *mid.SecurePolicy
+	maxBodySize      int64
+	templates        *web.Templates
+	timeout          time.Duration
+	tracer           *tracer.Tracer
+	metrics          *metrics.Registry
+	limiter          *mid.LimiterConfig
+	csrf             *csrf.Protector
+	notFound         web.HandlerFunc
+	methodNotAllowed web.HandlerFunc
 }
 
 // WithCORS provides configuration options for CORS.
-func WithCORS(origins []string) func(opts *Options) {
+func WithCORS(policy web.CORSPolicy) func(opts *Options) {
 	return func(opts *Options) {
-		opts.corsOrigin = origins
+		opts.cors = &policy
+	}
+}
+
+// WithSecureHeaders replaces the security headers policy derived from the
+// build mode.
+func WithSecureHeaders(policy mid.SecurePolicy) func(opts *Options) {
+	return func(opts *Options) {
+		opts.secure = &policy
+	}
+}
+
+// WithMaxBodySize sets the maximum size of a request body for every route.
+func WithMaxBodySize(n int64) func(opts *Options) {
+	return func(opts *Options) {
+		opts.maxBodySize = n
+	}
+}
+
+// WithTemplates sets the templates used to render HTML pages.
+func WithTemplates(t *web.Templates) func(opts *Options) {
+	return func(opts *Options) {
+		opts.templates = t
+	}
+}
+
+// WithTimeout sets the maximum time a request can take. Routes can use a
+// shorter timeout with mid.Timeout, and long lived routes like WebSocket and
+// event stream routes must opt out with mid.NoTimeout.
+func WithTimeout(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.timeout = d
+	}
+}
+
+// WithTracer enables tracing of the requests and of the calls made with
+// their context.
+func WithTracer(t *tracer.Tracer) func(opts *Options) {
+	return func(opts *Options) {
+		opts.tracer = t
+	}
+}
+
+// WithMetrics records the metrics of the requests in the registry.
+func WithMetrics(reg *metrics.Registry) func(opts *Options) {
+	return func(opts *Options) {
+		opts.metrics = reg
+	}
+}
+
+// WithLimiter limits the rate of requests of every client. Routes that
+// count by user should add mid.Limiter after mid.Authenticate instead.
+func WithLimiter(cfg mid.LimiterConfig) func(opts *Options) {
+	return func(opts *Options) {
+		opts.limiter = &cfg
+	}
+}
+
+// WithCSRF replaces the CSRF protector, which by default uses double submit
+// cookies signed with the CSRFSecret of the configuration.
+func WithCSRF(p *csrf.Protector) func(opts *Options) {
+	return func(opts *Options) {
+		opts.csrf = p
+	}
+}
+
+// WithNotFound replaces the handler used when no route matches the request.
+func WithNotFound(handler web.HandlerFunc) func(opts *Options) {
+	return func(opts *Options) {
+		opts.notFound = handler
+	}
+}
+
+// WithMethodNotAllowed replaces the handler used when a route matches the
+// path but not the method of the request.
+func WithMethodNotAllowed(handler web.HandlerFunc) func(opts *Options) {
+	return func(opts *Options) {
+		opts.methodNotAllowed = handler
 	}
 }
 
 type Config struct {
 	Build string
 	Log   *logger.Logger
+
+	// CSRFSecret signs the CSRF tokens and must be shared by every replica
+	// so a token issued by one is accepted by the others. It's required
+	// outside of the develop build.
+	CSRFSecret []byte
 }
 
 type RouteAdder interface {
 	Add(app *web.App, cfg Config)
 }
 
-func WebAPI(cfg Config, routeAdder RouteAdder, options ...func(opts *Options)) http.Handler {
-	app := web.NewApp(
-		cfg.Log.Info,
+// WebAPI constructs the handler of the service. It fails when the
+// configuration can't be used, like a missing CSRF secret in release.
+func WebAPI(cfg Config, routeAdder RouteAdder, options ...func(opts *Options)) (http.Handler, error) {
+	opts := Options{
+		notFound:         notFound,
+		methodNotAllowed: methodNotAllowed,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	secure := mid.DefaultSecurePolicy(cfg.Build)
+	if opts.secure != nil {
+		secure = *opts.secure
+	}
+
+	protector := opts.csrf
+	if protector == nil {
+		// A random secret only works for a single process, which is fine
+		// while developing but breaks tokens across replicas.
+		if len(cfg.CSRFSecret) == 0 && cfg.Build != "develop" {
+			return nil, errors.New("mux: a CSRF secret is required outside of develop")
+		}
+
+		p, err := csrf.New(csrf.Config{
+			Secret: cfg.CSRFSecret,
+			Secure: cfg.Build != "develop",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("mux: %w", err)
+		}
+		protector = p
+	}
+
+	mw := []web.MidFunc{
+		mid.RequestID(),
+		mid.Compress(mid.DefaultCompressConfig()),
 		mid.Logger(cfg.Log),
+	}
+
+	if opts.metrics != nil {
+		mw = append(mw, mid.Metrics(opts.metrics))
+	}
+
+	mw = append(mw,
 		mid.Errors(cfg.Log),
 		mid.Panics(),
-		mid.CSRF(),
 	)
 
-	var opts Options
-	for _, option := range options {
-		option(&opts)
+	if opts.limiter != nil {
+		mw = append(mw, mid.Limiter(cfg.Log, *opts.limiter))
+	}
+
+	mw = append(mw,
+		mid.SecureHeaders(secure),
+		mid.CSRF(protector),
+	)
+
+	if opts.timeout > 0 {
+		mw = append(mw, mid.Timeout(opts.timeout))
+	}
+
+	app := web.NewApp(cfg.Log.Info, mw...)
+
+	app.NotFound(opts.notFound)
+	app.MethodNotAllowed(opts.methodNotAllowed)
+
+	if opts.maxBodySize != 0 {
+		app.MaxBodySize(opts.maxBodySize)
+	}
+
+	if opts.templates != nil {
+		app.Templates(opts.templates)
+	}
+
+	if opts.tracer != nil {
+		app.Tracer(opts.tracer)
 	}
 
-	if len(opts.corsOrigin) > 0 {
-		app.EnableCORS(opts.corsOrigin)
+	if opts.cors != nil {
+		app.EnableCORS(*opts.cors)
 	}
 
 	routeAdder.Add(app, cfg)
-	return app
+	return app, nil
+}
+
+func notFound(ctx context.Context, r *http.Request) web.Encoder {
+	return errs.Newf(errs.NotFound, "Route %s not found", r.URL.Path)
+}
+
+func methodNotAllowed(ctx context.Context, r *http.Request) web.Encoder {
+	return errs.Newf(errs.MethodNotAllowed, "Method %s is not allowed", r.Method)
 }
@@ -0,0 +1,43 @@
+package mux
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+type noRoutes struct{}
+
+func (noRoutes) Add(app *web.App, cfg Config) {}
+
+func TestWebAPICSRFSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   string
+		secret  []byte
+		wantErr bool
+	}{
+		{name: "develop without secret", build: "develop"},
+		{name: "release without secret", build: "release", wantErr: true},
+		{name: "release with secret", build: "release", secret: bytes.Repeat([]byte("s"), 32)},
+		{name: "short secret", build: "release", secret: []byte("short"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Build:      tt.build,
+				Log:        logger.New(io.Discard, logger.LevelInfo, "TEST"),
+				CSRFSecret: tt.secret,
+			}
+
+			_, err := WebAPI(cfg, noRoutes{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package cache provides a generic cache with TTL, bounded memory storage
+// and loading of missing values, over a pluggable backend.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Backend stores the values of a cache. A ttl of zero or less uses the
+// default of the backend. Implementations must be safe for concurrent use,
+// so a networked backend can replace the memory one.
+type Backend[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, bool, error)
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+	Delete(ctx context.Context, key K) error
+}
+
+var errLoaderPanic = errors.New("cache: loader panicked")
+
+// LoaderFunc loads the value of a missing key.
+type LoaderFunc[V any] func(ctx context.Context) (V, error)
+
+// Cache reads and writes through a backend and loads missing values once
+// however many callers ask for them at the same time.
+type Cache[K comparable, V any] struct {
+	backend Backend[K, V]
+
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New constructs a cache over the backend.
+func New[K comparable, V any](backend Backend[K, V]) *Cache[K, V] {
+	return &Cache[K, V]{
+		backend: backend,
+		calls:   make(map[K]*call[V]),
+	}
+}
+
+// Get returns the value of the key.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	return c.backend.Get(ctx, key)
+}
+
+// Set sets the value of the key for the ttl.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	return c.backend.Set(ctx, key, value, ttl)
+}
+
+// Delete removes the key.
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) error {
+	return c.backend.Delete(ctx, key)
+}
+
+// GetOrLoad returns the value of the key, calling the loader when the key
+// is missing and caching its value for the ttl. Concurrent callers for the
+// same key wait for a single load, which runs with the values of the context
+// of the first caller but isn't canceled with it, since other callers may be
+// waiting. Errors of the loader aren't cached.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, ttl time.Duration, loader LoaderFunc[V]) (V, error) {
+	if v, ok, err := c.backend.Get(ctx, key); err == nil && ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+	if cl, exists := c.calls[key]; exists {
+		c.mu.Unlock()
+
+		select {
+		case <-cl.done:
+			return cl.value, cl.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+
+	cl := call[V]{done: make(chan struct{})}
+	c.calls[key] = &cl
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+
+		close(cl.done)
+	}()
+
+	// Waiters receive this error if the loader panics.
+	cl.err = errLoaderPanic
+
+	lctx := context.WithoutCancel(ctx)
+
+	cl.value, cl.err = loader(lctx)
+	if cl.err != nil {
+		return cl.value, cl.err
+	}
+
+	// The value is returned even if it can't be cached.
+	c.backend.Set(lctx, key, cl.value, ttl)
+
+	return cl.value, nil
+}
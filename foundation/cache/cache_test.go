@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetOrLoadCanceled(t *testing.T) {
+	c := New[string, int](NewMemory[string, int]())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "key", 0, func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, ctx.Err()
+		})
+		first <- err
+	}()
+
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "key", 0, func(ctx context.Context) (int, error) {
+			return 2, nil
+		})
+		second <- v
+	}()
+
+	cancel()
+	close(release)
+
+	if err := <-first; err != nil {
+		t.Fatalf("got %s, want the load to ignore the cancellation of the first caller", err)
+	}
+	if v := <-second; v != 1 {
+		t.Errorf("got %d, want the shared value 1", v)
+	}
+}
@@ -0,0 +1,382 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Reason tells why a value left the memory backend.
+type Reason int
+
+// Set of reasons.
+const (
+	Expired Reason = iota + 1
+	Evicted
+	Deleted
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Evicted:
+		return "evicted"
+	case Deleted:
+		return "deleted"
+	}
+
+	return "unknown"
+}
+
+// MemoryOptions represent optional parameters.
+type MemoryOptions[K comparable, V any] struct {
+	ttl        time.Duration
+	shards     int
+	maxEntries int
+	maxCost    int64
+	cost       func(V) int64
+	onEvict    func(key K, value V, reason Reason)
+	cleanup    time.Duration
+	now        func() time.Time
+}
+
+// WithTTL sets the default time to live of the values. Zero keeps the
+// values until they are evicted.
+func WithTTL[K comparable, V any](d time.Duration) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.ttl = d
+	}
+}
+
+// WithShards sets the number of independently locked shards. More shards
+// reduce the contention between goroutines.
+func WithShards[K comparable, V any](n int) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.shards = n
+	}
+}
+
+// WithMaxEntries bounds the number of values. The bound is split evenly
+// between the shards and the least recently used values of a shard are
+// evicted first, so the cache can hold fewer values than the bound when
+// the keys don't spread evenly. The number of shards is reduced to the
+// bound when it's smaller.
+func WithMaxEntries[K comparable, V any](n int) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.maxEntries = n
+	}
+}
+
+// WithMaxCost bounds the total cost of the values, like their size in
+// bytes, as returned by the cost function. Like the entries bound, it is
+// split evenly between the shards.
+func WithMaxCost[K comparable, V any](maxCost int64, cost func(V) int64) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.maxCost = maxCost
+		opts.cost = cost
+	}
+}
+
+// WithOnEvict sets the function called when a value leaves the cache. It is
+// called without holding any lock, so it can use the cache.
+func WithOnEvict[K comparable, V any](fn func(key K, value V, reason Reason)) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.onEvict = fn
+	}
+}
+
+// WithCleanupInterval removes the expired values in the background at the
+// interval. Otherwise they are removed when read or evicted. Call Close to
+// stop the cleanup.
+func WithCleanupInterval[K comparable, V any](d time.Duration) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.cleanup = d
+	}
+}
+
+// WithClock replaces the clock of the cache.
+func WithClock[K comparable, V any](now func() time.Time) func(opts *MemoryOptions[K, V]) {
+	return func(opts *MemoryOptions[K, V]) {
+		opts.now = now
+	}
+}
+
+// Memory is a backend keeping the values in the memory of the process,
+// split in shards each with its own lock and LRU list.
+type Memory[K comparable, V any] struct {
+	seed    maphash.Seed
+	shards  []*shard[K, V]
+	ttl     time.Duration
+	onEvict func(key K, value V, reason Reason)
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type shard[K comparable, V any] struct {
+	mu         sync.Mutex
+	items      map[K]*list.Element
+	lru        *list.List
+	maxEntries int
+	maxCost    int64
+	cost       func(V) int64
+	total      int64
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time
+}
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason Reason
+}
+
+// NewMemory constructs a memory backend with 16 shards and no bound by
+// default.
+func NewMemory[K comparable, V any](options ...func(opts *MemoryOptions[K, V])) *Memory[K, V] {
+	opts := MemoryOptions[K, V]{
+		shards: 16,
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	opts.shards = max(1, opts.shards)
+	if opts.maxEntries > 0 {
+		opts.shards = min(opts.shards, opts.maxEntries)
+	}
+
+	m := Memory[K, V]{
+		seed:    maphash.MakeSeed(),
+		shards:  make([]*shard[K, V], opts.shards),
+		ttl:     opts.ttl,
+		onEvict: opts.onEvict,
+		now:     opts.now,
+		stop:    make(chan struct{}),
+	}
+
+	for i := range m.shards {
+		s := shard[K, V]{
+			items: make(map[K]*list.Element),
+			lru:   list.New(),
+			cost:  opts.cost,
+		}
+		if opts.maxEntries > 0 {
+			s.maxEntries = max(1, opts.maxEntries/opts.shards)
+		}
+		if opts.maxCost > 0 && opts.cost != nil {
+			s.maxCost = max(1, opts.maxCost/int64(opts.shards))
+		}
+		m.shards[i] = &s
+	}
+
+	if opts.cleanup > 0 {
+		go m.cleanup(opts.cleanup)
+	}
+
+	return &m
+}
+
+// Get implements the Backend interface.
+func (m *Memory[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	v, ok := m.Load(key)
+	return v, ok, nil
+}
+
+// Set implements the Backend interface.
+func (m *Memory[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	m.Store(key, value, ttl)
+	return nil
+}
+
+// Delete implements the Backend interface.
+func (m *Memory[K, V]) Delete(ctx context.Context, key K) error {
+	m.Remove(key)
+	return nil
+}
+
+// Load returns the value of the key unless it is missing or expired.
+func (m *Memory[K, V]) Load(key K) (V, bool) {
+	s := m.shard(key)
+	now := m.now()
+
+	s.mu.Lock()
+
+	elem, exists := s.items[key]
+	if !exists {
+		s.mu.Unlock()
+		var zero V
+		return zero, false
+	}
+
+	e := elem.Value.(*entry[K, V])
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		s.remove(elem)
+		s.mu.Unlock()
+
+		m.notify(eviction[K, V]{key: e.key, value: e.value, reason: Expired})
+		var zero V
+		return zero, false
+	}
+
+	s.lru.MoveToFront(elem)
+	s.mu.Unlock()
+
+	return e.value, true
+}
+
+// Store sets the value of the key for the ttl, or for the default ttl when
+// the ttl is zero or less.
+func (m *Memory[K, V]) Store(key K, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = m.ttl
+	}
+
+	e := entry[K, V]{key: key, value: value}
+	if ttl > 0 {
+		e.expires = m.now().Add(ttl)
+	}
+
+	s := m.shard(key)
+
+	s.mu.Lock()
+
+	if s.cost != nil {
+		e.cost = s.cost(value)
+	}
+
+	if elem, exists := s.items[key]; exists {
+		s.remove(elem)
+	}
+	s.items[key] = s.lru.PushFront(&e)
+	s.total += e.cost
+
+	var evicted []eviction[K, V]
+	for s.over() {
+		back := s.lru.Back()
+		old := back.Value.(*entry[K, V])
+		s.remove(back)
+		evicted = append(evicted, eviction[K, V]{key: old.key, value: old.value, reason: Evicted})
+	}
+
+	s.mu.Unlock()
+
+	m.notify(evicted...)
+}
+
+// Remove removes the key.
+func (m *Memory[K, V]) Remove(key K) {
+	s := m.shard(key)
+
+	s.mu.Lock()
+	elem, exists := s.items[key]
+	if !exists {
+		s.mu.Unlock()
+		return
+	}
+	e := elem.Value.(*entry[K, V])
+	s.remove(elem)
+	s.mu.Unlock()
+
+	m.notify(eviction[K, V]{key: e.key, value: e.value, reason: Deleted})
+}
+
+// Len returns the number of values, including the expired values not yet
+// removed.
+func (m *Memory[K, V]) Len() int {
+	var n int
+	for _, s := range m.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+
+	return n
+}
+
+// DeleteExpired removes the expired values.
+func (m *Memory[K, V]) DeleteExpired() {
+	now := m.now()
+
+	for _, s := range m.shards {
+		var expired []eviction[K, V]
+
+		s.mu.Lock()
+		for elem := s.lru.Front(); elem != nil; {
+			next := elem.Next()
+			if e := elem.Value.(*entry[K, V]); !e.expires.IsZero() && !now.Before(e.expires) {
+				s.remove(elem)
+				expired = append(expired, eviction[K, V]{key: e.key, value: e.value, reason: Expired})
+			}
+			elem = next
+		}
+		s.mu.Unlock()
+
+		m.notify(expired...)
+	}
+}
+
+// Close stops the background cleanup.
+func (m *Memory[K, V]) Close() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
+
+func (m *Memory[K, V]) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.DeleteExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *Memory[K, V]) shard(key K) *shard[K, V] {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+
+	return m.shards[maphash.Comparable(m.seed, key)%uint64(len(m.shards))]
+}
+
+func (m *Memory[K, V]) notify(evicted ...eviction[K, V]) {
+	if m.onEvict == nil {
+		return
+	}
+
+	for _, ev := range evicted {
+		m.onEvict(ev.key, ev.value, ev.reason)
+	}
+}
+
+// over reports whether the shard exceeds its bounds. The most recent value
+// is always kept, even if it exceeds the cost bound alone.
+func (s *shard[K, V]) over() bool {
+	if s.lru.Len() <= 1 {
+		return false
+	}
+
+	return (s.maxEntries > 0 && s.lru.Len() > s.maxEntries) || (s.maxCost > 0 && s.total > s.maxCost)
+}
+
+func (s *shard[K, V]) remove(elem *list.Element) {
+	e := elem.Value.(*entry[K, V])
+	s.lru.Remove(elem)
+	delete(s.items, e.key)
+	s.total -= e.cost
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestMemoryLRU(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []func(opts *MemoryOptions[string, string])
+		store   []string
+		load    []string
+		evicted []string
+		kept    []string
+	}{
+		{
+			name:    "oldest",
+			options: []func(opts *MemoryOptions[string, string]){WithMaxEntries[string, string](2)},
+			store:   []string{"a", "b", "c"},
+			evicted: []string{"a"},
+			kept:    []string{"b", "c"},
+		},
+		{
+			name:    "least recently used",
+			options: []func(opts *MemoryOptions[string, string]){WithMaxEntries[string, string](2)},
+			store:   []string{"a", "b"},
+			load:    []string{"a"},
+			evicted: []string{"b"},
+			kept:    []string{"a", "c"},
+		},
+		{
+			name: "cost",
+			options: []func(opts *MemoryOptions[string, string]){
+				WithMaxCost[string, string](3, func(v string) int64 { return int64(len(v)) }),
+			},
+			store:   []string{"a", "bb", "c"},
+			evicted: []string{"a"},
+			kept:    []string{"bb", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var evicted []string
+			options := append(tt.options,
+				WithShards[string, string](1),
+				WithOnEvict(func(key string, value string, reason Reason) {
+					if reason != Evicted {
+						t.Errorf("got reason %s for %s, want %s", reason, key, Evicted)
+					}
+					evicted = append(evicted, key)
+				}),
+			)
+			m := NewMemory(options...)
+
+			for _, key := range tt.store {
+				m.Store(key, key, 0)
+			}
+			for _, key := range tt.load {
+				m.Load(key)
+			}
+			if len(tt.load) > 0 {
+				m.Store("c", "c", 0)
+			}
+
+			if !slices.Equal(evicted, tt.evicted) {
+				t.Errorf("got evicted %v, want %v", evicted, tt.evicted)
+			}
+			for _, key := range tt.kept {
+				if _, ok := m.Load(key); !ok {
+					t.Errorf("got %s missing, want kept", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryShardsBound(t *testing.T) {
+	m := NewMemory(WithShards[int, int](16), WithMaxEntries[int, int](4))
+
+	if got := len(m.shards); got != 4 {
+		t.Errorf("got %d shards, want 4", got)
+	}
+
+	for i := range 100 {
+		m.Store(i, i, 0)
+	}
+
+	if got := m.Len(); got > 4 {
+		t.Errorf("got %d values, want at most 4", got)
+	}
+}
+
+func TestMemoryTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		def     time.Duration
+		advance time.Duration
+		found   bool
+	}{
+		{name: "fresh", ttl: time.Minute, advance: 59 * time.Second, found: true},
+		{name: "expired", ttl: time.Minute, advance: time.Minute, found: false},
+		{name: "default", def: time.Second, advance: time.Second, found: false},
+		{name: "no ttl", advance: time.Hour, found: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock{now: time.Unix(0, 0)}
+
+			var reason Reason
+			m := NewMemory(
+				WithTTL[string, int](tt.def),
+				WithClock[string, int](clk.Now),
+				WithOnEvict(func(key string, value int, r Reason) { reason = r }),
+			)
+
+			m.Store("key", 1, tt.ttl)
+			clk.Advance(tt.advance)
+
+			_, ok := m.Load("key")
+			if ok != tt.found {
+				t.Errorf("got found %t, want %t", ok, tt.found)
+			}
+			if !tt.found && reason != Expired {
+				t.Errorf("got reason %s, want %s", reason, Expired)
+			}
+		})
+	}
+}
+
+func TestMemoryDeleteExpired(t *testing.T) {
+	clk := clock{now: time.Unix(0, 0)}
+	m := NewMemory(WithClock[string, int](clk.Now))
+
+	m.Store("short", 1, time.Second)
+	m.Store("long", 2, time.Hour)
+	clk.Advance(time.Minute)
+
+	m.DeleteExpired()
+
+	if got := m.Len(); got != 1 {
+		t.Errorf("got %d values, want 1", got)
+	}
+	if _, ok := m.Load("long"); !ok {
+		t.Error("got long missing, want kept")
+	}
+}
@@ -0,0 +1,366 @@
+// Package csrf protects cookie authenticated applications against cross
+// site request forgery with HMAC signed tokens bound to the session of the
+// client.
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// Set of errors returned when a request fails the verification.
+var (
+	ErrTokenMissing  = errors.New("the csrf token is required")
+	ErrTokenInvalid  = errors.New("invalid csrf token")
+	ErrTokenExpired  = errors.New("the csrf token expired")
+	ErrTokenMismatch = errors.New("the csrf token doesn't match the csrf cookie")
+)
+
+// Mode selects how a submitted token is verified.
+type Mode int
+
+// Set of modes.
+const (
+	// DoubleSubmit requires the token of the header or form to be the token
+	// of the cookie. No state is kept on the server.
+	DoubleSubmit Mode = iota
+
+	// ServerStore requires the token to be saved in the store for the
+	// session, so tokens can be revoked.
+	ServerStore
+)
+
+// SessionFunc returns the identifier of the session of the client, like the
+// value of the session cookie. Tokens are only valid for the session they
+// were issued for.
+type SessionFunc func(ctx context.Context, r *http.Request) string
+
+// Config configures a Protector. Zero values use the defaults.
+type Config struct {
+	// Secret signs the tokens and must be at least 32 bytes. Replicas must
+	// share it. A random secret is generated when empty.
+	Secret []byte
+
+	// Session binds the tokens to the session of the client. Without it
+	// tokens are only bound to the browser holding the cookie.
+	Session SessionFunc
+
+	Mode  Mode
+	Store Store
+
+	CookieName string
+	HeaderName string
+	FormField  string
+	CookiePath string
+	Domain     string
+	Secure     bool
+	HTTPOnly   bool
+	SameSite   http.SameSite
+
+	// MaxAge is the lifetime of a token.
+	MaxAge time.Duration
+
+	// RotateAfter replaces a valid token older than this duration when it
+	// is used. Zero disables the rotation.
+	RotateAfter time.Duration
+
+	// Exempt lists route patterns, like "POST /api/webhook" or
+	// "/api/webhook" for every method, that aren't verified.
+	Exempt []string
+}
+
+// Protector issues and verifies CSRF tokens.
+type Protector struct {
+	cfg Config
+}
+
+// Defaults of the configuration.
+const (
+	DefaultCookieName = "csrftoken"
+	DefaultHeaderName = "X-CSRF-Token"
+	DefaultFormField  = "csrf_token"
+	DefaultMaxAge     = 12 * time.Hour
+)
+
+// New constructs a protector. The cookie is readable by scripts in double
+// submit mode so single page applications can copy it to the header.
+func New(cfg Config) (*Protector, error) {
+	switch {
+	case len(cfg.Secret) == 0:
+		cfg.Secret = make([]byte, 32)
+		rand.Read(cfg.Secret)
+	case len(cfg.Secret) < 32:
+		return nil, fmt.Errorf("csrf: secret must be at least 32 bytes, got %d", len(cfg.Secret))
+	}
+
+	if cfg.Mode == ServerStore && cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+
+	if cfg.Session == nil {
+		cfg.Session = func(context.Context, *http.Request) string { return "" }
+	}
+
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCookieName
+	}
+
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = DefaultHeaderName
+	}
+
+	if cfg.FormField == "" {
+		cfg.FormField = DefaultFormField
+	}
+
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+
+	return &Protector{cfg: cfg}, nil
+}
+
+// Default constructs a double submit protector with a random secret, which
+// only suits a single replica.
+func Default(secure bool) *Protector {
+	p, _ := New(Config{Secure: secure})
+	return p
+}
+
+// Exempt reports whether the route of the request isn't verified.
+func (p *Protector) Exempt(r *http.Request) bool {
+	if r.Pattern == "" || len(p.cfg.Exempt) == 0 {
+		return false
+	}
+
+	if slices.Contains(p.cfg.Exempt, r.Pattern) {
+		return true
+	}
+
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return slices.Contains(p.cfg.Exempt, path)
+	}
+
+	return false
+}
+
+// Token returns the valid token of the client or issues a new one. The
+// token is available to the handlers with web.CSRFToken.
+func (p *Protector) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) (string, error) {
+	if token := web.CSRFToken(ctx); token != "" {
+		return token, nil
+	}
+
+	if c, err := r.Cookie(p.cfg.CookieName); err == nil {
+		issued, err := p.verify(ctx, r, c.Value)
+		if err == nil && !p.rotationDue(issued) {
+			web.SetCSRFToken(ctx, c.Value)
+			return c.Value, nil
+		}
+	}
+
+	return p.Issue(ctx, w, r)
+}
+
+// Issue issues a new token for the session of the request and sets the
+// cookie.
+func (p *Protector) Issue(ctx context.Context, w http.ResponseWriter, r *http.Request) (string, error) {
+	session := p.cfg.Session(ctx, r)
+	token := p.sign(session, time.Now())
+
+	if p.cfg.Mode == ServerStore {
+		if err := p.cfg.Store.Save(ctx, session, token, p.cfg.MaxAge); err != nil {
+			return "", fmt.Errorf("csrf: save: %w", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.cfg.CookieName,
+		Value:    token,
+		Path:     p.cfg.CookiePath,
+		Domain:   p.cfg.Domain,
+		MaxAge:   int(p.cfg.MaxAge / time.Second),
+		Secure:   p.cfg.Secure,
+		HttpOnly: p.cfg.HTTPOnly,
+		SameSite: p.cfg.SameSite,
+	})
+
+	web.SetCSRFToken(ctx, token)
+
+	return token, nil
+}
+
+// Rotate revokes the token of the client and issues a new one. Call it when
+// the privileges of the session change, like after a login.
+func (p *Protector) Rotate(ctx context.Context, w http.ResponseWriter, r *http.Request) (string, error) {
+	if p.cfg.Mode == ServerStore {
+		if c, err := r.Cookie(p.cfg.CookieName); err == nil {
+			if err := p.cfg.Store.Delete(ctx, p.cfg.Session(ctx, r), c.Value); err != nil {
+				return "", fmt.Errorf("csrf: delete: %w", err)
+			}
+		}
+	}
+
+	return p.Issue(ctx, w, r)
+}
+
+// Verify checks the token submitted in the header, or in the field of an
+// url encoded form. It reports if the token should be rotated.
+func (p *Protector) Verify(ctx context.Context, r *http.Request) (rotate bool, err error) {
+	token := p.submitted(r)
+	if token == "" {
+		return false, ErrTokenMissing
+	}
+
+	if p.cfg.Mode == DoubleSubmit {
+		c, err := r.Cookie(p.cfg.CookieName)
+		if err != nil {
+			return false, ErrTokenMissing
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.Value), []byte(token)) != 1 {
+			return false, ErrTokenMismatch
+		}
+	}
+
+	issued, err := p.verify(ctx, r, token)
+	if err != nil {
+		return false, err
+	}
+
+	return p.rotationDue(issued), nil
+}
+
+// Handler returns a handler answering the token of the client, issuing one
+// when needed, for applications that can't read the cookie.
+func (p *Protector) Handler() web.HandlerFunc {
+	return func(ctx context.Context, r *http.Request) web.Encoder {
+		w := web.GetWriter(ctx)
+
+		token, err := p.Token(ctx, w, r)
+		if err != nil {
+			return web.NewError(http.StatusInternalServerError, "csrf: %s", err)
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		return web.JSON(http.StatusOK, struct {
+			Token  string `json:"token"`
+			Header string `json:"header"`
+		}{
+			Token:  token,
+			Header: p.cfg.HeaderName,
+		})
+	}
+}
+
+// =============================================================================
+
+// Tokens are made of a random nonce and the time they were issued, followed
+// by the signature of both with the session.
+const (
+	nonceLen   = 16
+	payloadLen = nonceLen + 8
+)
+
+func (p *Protector) sign(session string, now time.Time) string {
+	payload := make([]byte, payloadLen)
+	rand.Read(payload[:nonceLen])
+	binary.BigEndian.PutUint64(payload[nonceLen:], uint64(now.Unix()))
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(p.mac(session, payload))
+}
+
+func (p *Protector) mac(session string, payload []byte) []byte {
+	h := hmac.New(sha256.New, p.cfg.Secret)
+	binary.Write(h, binary.BigEndian, uint32(len(session)))
+	h.Write([]byte(session))
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// verify checks the signature, age and, in store mode, the existence of the
+// token. It returns the time the token was issued.
+func (p *Protector) verify(ctx context.Context, r *http.Request, token string) (time.Time, error) {
+	encPayload, encMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return time.Time{}, ErrTokenInvalid
+	}
+
+	enc := base64.RawURLEncoding
+
+	payload, err := enc.DecodeString(encPayload)
+	if err != nil || len(payload) != payloadLen {
+		return time.Time{}, ErrTokenInvalid
+	}
+
+	mac, err := enc.DecodeString(encMAC)
+	if err != nil {
+		return time.Time{}, ErrTokenInvalid
+	}
+
+	session := p.cfg.Session(ctx, r)
+	if !hmac.Equal(mac, p.mac(session, payload)) {
+		return time.Time{}, ErrTokenInvalid
+	}
+
+	issued := time.Unix(int64(binary.BigEndian.Uint64(payload[nonceLen:])), 0)
+	if time.Since(issued) > p.cfg.MaxAge {
+		return time.Time{}, ErrTokenExpired
+	}
+
+	if p.cfg.Mode == ServerStore {
+		exists, err := p.cfg.Store.Exists(ctx, session, token)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("csrf: exists: %w", err)
+		}
+		if !exists {
+			return time.Time{}, ErrTokenInvalid
+		}
+	}
+
+	return issued, nil
+}
+
+func (p *Protector) rotationDue(issued time.Time) bool {
+	return p.cfg.RotateAfter > 0 && time.Since(issued) > p.cfg.RotateAfter
+}
+
+// submitted returns the token of the header, or of the form field for url
+// encoded forms. Multipart forms must use the header so the body can still
+// be streamed by the handler.
+func (p *Protector) submitted(r *http.Request) string {
+	if token := r.Header.Get(p.cfg.HeaderName); token != "" {
+		return token
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		return r.PostFormValue(p.cfg.FormField)
+	}
+
+	return ""
+}
@@ -0,0 +1,50 @@
+package csrf
+
+import (
+	"context"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/cache"
+)
+
+// Store keeps the tokens issued to every session in server store mode.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Save(ctx context.Context, session, token string, ttl time.Duration) error
+	Exists(ctx context.Context, session, token string) (bool, error)
+	Delete(ctx context.Context, session, token string) error
+}
+
+// MemoryStore keeps the tokens in the memory of the process, which only
+// suits a single replica. The least recently used tokens are evicted past
+// 100,000 tokens.
+type MemoryStore struct {
+	tokens *cache.Memory[string, struct{}]
+}
+
+// NewMemoryStore constructs an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens: cache.NewMemory(cache.WithMaxEntries[string, struct{}](100_000)),
+	}
+}
+
+// Save implements the Store interface.
+func (s *MemoryStore) Save(ctx context.Context, session, token string, ttl time.Duration) error {
+	return s.tokens.Set(ctx, storeKey(session, token), struct{}{}, ttl)
+}
+
+// Exists implements the Store interface.
+func (s *MemoryStore) Exists(ctx context.Context, session, token string) (bool, error) {
+	_, exists, err := s.tokens.Get(ctx, storeKey(session, token))
+	return exists, err
+}
+
+// Delete implements the Store interface.
+func (s *MemoryStore) Delete(ctx context.Context, session, token string) error {
+	return s.tokens.Delete(ctx, storeKey(session, token))
+}
+
+func storeKey(session, token string) string {
+	return session + "\x00" + token
+}
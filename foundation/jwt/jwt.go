@@ -0,0 +1,355 @@
+// Package jwt verifies JSON Web Tokens signed with RS256, ES256, EdDSA or
+// HS256 without calling the service that issued them.
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Set of errors returned when a token is rejected.
+var (
+	ErrTokenMalformed    = errors.New("jwt: malformed token")
+	ErrAlgorithm         = errors.New("jwt: algorithm not allowed")
+	ErrUnknownKey        = errors.New("jwt: unknown signing key")
+	ErrSignatureInvalid  = errors.New("jwt: invalid signature")
+	ErrTokenExpired      = errors.New("jwt: token expired")
+	ErrTokenNotYetValid  = errors.New("jwt: token not valid yet")
+	ErrTokenUsedTooEarly = errors.New("jwt: token issued in the future")
+	ErrInvalidIssuer     = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience   = errors.New("jwt: invalid audience")
+)
+
+// Set of supported algorithms.
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+	HS256 = "HS256"
+)
+
+// NumericDate is a time encoded as seconds since the unix epoch.
+type NumericDate struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+
+	sec, frac := math.Modf(f)
+	d.Time = time.Unix(int64(sec), int64(frac*1e9))
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d NumericDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Unix())
+}
+
+// Audience is the aud claim, which is a string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Claims are the registered claims of a token. Every claim, including the
+// private ones, is available in Raw.
+type Claims struct {
+	Issuer    string         `json:"iss,omitempty"`
+	Subject   string         `json:"sub,omitempty"`
+	Audience  Audience       `json:"aud,omitempty"`
+	ExpiresAt *NumericDate   `json:"exp,omitempty"`
+	NotBefore *NumericDate   `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate   `json:"iat,omitempty"`
+	ID        string         `json:"jti,omitempty"`
+	Raw       map[string]any `json:"-"`
+}
+
+// Decode unmarshals the claims, including the private ones, into v.
+func (c Claims) Decode(v any) error {
+	data, err := json.Marshal(c.Raw)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// =============================================================================
+
+// Options represent optional parameters.
+type Options struct {
+	issuer     string
+	audience   string
+	skew       time.Duration
+	algorithms []string
+	requireExp bool
+	now        func() time.Time
+}
+
+// WithIssuer requires the iss claim to be the issuer.
+func WithIssuer(issuer string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain the audience.
+func WithAudience(audience string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.audience = audience
+	}
+}
+
+// WithClockSkew sets the tolerance applied to the time claims for clocks
+// that are not synchronized.
+func WithClockSkew(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.skew = d
+	}
+}
+
+// WithAlgorithms restricts the algorithms accepted.
+func WithAlgorithms(algorithms ...string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.algorithms = algorithms
+	}
+}
+
+// WithoutExpiration accepts tokens without an exp claim.
+func WithoutExpiration() func(opts *Options) {
+	return func(opts *Options) {
+		opts.requireExp = false
+	}
+}
+
+// WithClock replaces the clock of the verifier.
+func WithClock(now func() time.Time) func(opts *Options) {
+	return func(opts *Options) {
+		opts.now = now
+	}
+}
+
+// Verifier verifies the signature and claims of tokens.
+type Verifier struct {
+	keys       KeySet
+	issuer     string
+	audience   string
+	skew       time.Duration
+	algorithms []string
+	requireExp bool
+	now        func() time.Time
+}
+
+// NewVerifier constructs a verifier finding the keys in the key set. Tokens
+// must expire and a minute of clock skew is tolerated by default.
+func NewVerifier(keys KeySet, options ...func(opts *Options)) *Verifier {
+	opts := Options{
+		skew:       time.Minute,
+		algorithms: []string{RS256, ES256, EdDSA, HS256},
+		requireExp: true,
+		now:        time.Now,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Verifier{
+		keys:       keys,
+		issuer:     opts.issuer,
+		audience:   opts.audience,
+		skew:       opts.skew,
+		algorithms: opts.algorithms,
+		requireExp: opts.requireExp,
+		now:        opts.now,
+	}
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// Verify verifies the token and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	if !slices.Contains(v.algorithms, h.Algorithm) {
+		return Claims{}, fmt.Errorf("%w: %q", ErrAlgorithm, h.Algorithm)
+	}
+
+	key, err := v.keys.Key(ctx, h.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if key.Algorithm != "" && key.Algorithm != h.Algorithm {
+		return Claims{}, fmt.Errorf("%w: key %q is for %s", ErrAlgorithm, h.KeyID, key.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	signed := parts[0] + "." + parts[1]
+	if err := verifySignature(h.Algorithm, key.Key, []byte(signed), signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	if err := v.validate(claims); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) validate(c Claims) error {
+	now := v.now()
+
+	switch {
+	case c.ExpiresAt == nil && v.requireExp:
+		return fmt.Errorf("%w: missing exp claim", ErrTokenExpired)
+	case c.ExpiresAt != nil && !now.Before(c.ExpiresAt.Add(v.skew)):
+		return ErrTokenExpired
+	}
+
+	if c.NotBefore != nil && now.Add(v.skew).Before(c.NotBefore.Time) {
+		return ErrTokenNotYetValid
+	}
+
+	if c.IssuedAt != nil && now.Add(v.skew).Before(c.IssuedAt.Time) {
+		return ErrTokenUsedTooEarly
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+// verifySignature checks the signature with a key of the type the algorithm
+// requires, so a public key can never be used as an HMAC secret.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 requires an RSA key", ErrAlgorithm)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrSignatureInvalid
+		}
+
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().Name != "P-256" {
+			return fmt.Errorf("%w: ES256 requires a P-256 key", ErrAlgorithm)
+		}
+		if len(signature) != 64 {
+			return ErrSignatureInvalid
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrSignatureInvalid
+		}
+
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: EdDSA requires an Ed25519 key", ErrAlgorithm)
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return ErrSignatureInvalid
+		}
+
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: HS256 requires a secret", ErrAlgorithm)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignatureInvalid
+		}
+
+	default:
+		return fmt.Errorf("%w: %q", ErrAlgorithm, alg)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// sign builds a token signed with the private key, or with the secret for
+// HS256.
+func sign(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	h, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("marshal header: %s", err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %s", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	default:
+		t.Fatalf("unsupported key %T", key)
+	}
+	if err != nil {
+		t.Fatalf("sign: %s", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %s", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %s", err)
+	}
+	secret := []byte("secret")
+
+	// The DER encoding of the public key is what an attacker would use as
+	// the HMAC secret in an algorithm confusion attack.
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal RSA key: %s", err)
+	}
+
+	keys := StaticKeys{
+		{ID: "rsa", Key: &rsaKey.PublicKey},
+		{ID: "ec", Algorithm: ES256, Key: &ecKey.PublicKey},
+		{ID: "ed", Key: edKey.Public()},
+		{ID: "hs", Algorithm: HS256, Key: secret},
+	}
+
+	claims := map[string]any{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name    string
+		token   string
+		options []func(opts *Options)
+		err     error
+	}{
+		{name: "RS256", token: sign(t, RS256, "rsa", rsaKey, claims)},
+		{name: "ES256", token: sign(t, ES256, "ec", ecKey, claims)},
+		{name: "EdDSA", token: sign(t, EdDSA, "ed", edKey, claims)},
+		{name: "HS256", token: sign(t, HS256, "hs", secret, claims)},
+		{name: "none", token: sign(t, "none", "rsa", secret, claims), err: ErrAlgorithm},
+		{name: "public key as secret", token: sign(t, HS256, "rsa", rsaDER, claims), err: ErrAlgorithm},
+		{name: "key bound to another algorithm", token: sign(t, HS256, "ec", secret, claims), err: ErrAlgorithm},
+		{name: "wrong key type", token: sign(t, ES256, "rsa", ecKey, claims), err: ErrAlgorithm},
+		{
+			name:    "algorithm not allowed",
+			token:   sign(t, HS256, "hs", secret, claims),
+			options: []func(opts *Options){WithAlgorithms(RS256)},
+			err:     ErrAlgorithm,
+		},
+		{name: "wrong signature", token: sign(t, HS256, "hs", []byte("other"), claims), err: ErrSignatureInvalid},
+		{name: "unknown key", token: sign(t, HS256, "missing", secret, claims), err: ErrUnknownKey},
+		{name: "malformed", token: "a.b", err: ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(keys, tt.options...)
+
+			c, err := v.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && c.Subject != "user" {
+				t.Errorf("got subject %q, want user", c.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	secret := []byte("secret")
+	keys := StaticKeys{{Algorithm: HS256, Key: secret}}
+	now := time.Unix(1_700_000_000, 0)
+
+	at := func(d time.Duration) int64 {
+		return now.Add(d).Unix()
+	}
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		options []func(opts *Options)
+		err     error
+	}{
+		{name: "valid", claims: map[string]any{"exp": at(time.Minute)}},
+		{name: "expired within skew", claims: map[string]any{"exp": at(-30 * time.Second)}},
+		{name: "expired", claims: map[string]any{"exp": at(-time.Minute)}, err: ErrTokenExpired},
+		{
+			name:    "expired without skew",
+			claims:  map[string]any{"exp": at(-time.Second)},
+			options: []func(opts *Options){WithClockSkew(0)},
+			err:     ErrTokenExpired,
+		},
+		{name: "missing exp", claims: map[string]any{}, err: ErrTokenExpired},
+		{name: "without expiration", claims: map[string]any{}, options: []func(opts *Options){WithoutExpiration()}},
+		{name: "not before within skew", claims: map[string]any{"exp": at(time.Hour), "nbf": at(30 * time.Second)}},
+		{name: "not yet valid", claims: map[string]any{"exp": at(time.Hour), "nbf": at(2 * time.Minute)}, err: ErrTokenNotYetValid},
+		{name: "issued in the future", claims: map[string]any{"exp": at(time.Hour), "iat": at(2 * time.Minute)}, err: ErrTokenUsedTooEarly},
+		{
+			name:    "issuer",
+			claims:  map[string]any{"exp": at(time.Hour), "iss": "other"},
+			options: []func(opts *Options){WithIssuer("issuer")},
+			err:     ErrInvalidIssuer,
+		},
+		{
+			name:    "audience",
+			claims:  map[string]any{"exp": at(time.Hour), "aud": []string{"a", "b"}},
+			options: []func(opts *Options){WithAudience("b")},
+		},
+		{
+			name:    "wrong audience",
+			claims:  map[string]any{"exp": at(time.Hour), "aud": "a"},
+			options: []func(opts *Options){WithAudience("b")},
+			err:     ErrInvalidAudience,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]func(opts *Options){WithClock(func() time.Time { return now })}, tt.options...)
+			v := NewVerifier(keys, options...)
+
+			_, err := v.Verify(context.Background(), sign(t, HS256, "", secret, tt.claims))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
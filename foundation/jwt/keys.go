@@ -0,0 +1,373 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/cache"
+)
+
+// Key is a verification key: an *rsa.PublicKey, an *ecdsa.PublicKey, an
+// ed25519.PublicKey or a []byte secret for HS256. When Algorithm is set
+// the key only verifies tokens signed with that algorithm.
+type Key struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+// KeySet finds the key of a token by the kid of its header.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (Key, error)
+}
+
+// StaticKeys is a key set that never changes, like the shared secret of
+// HS256 tokens. A key with an empty ID matches tokens without a kid.
+type StaticKeys []Key
+
+// Key implements the KeySet interface.
+func (ks StaticKeys) Key(ctx context.Context, kid string) (Key, error) {
+	for _, key := range ks {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+
+	return Key{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// =============================================================================
+
+// FetchFunc returns the JSON Web Key Set document of the issuer.
+type FetchFunc func(ctx context.Context) ([]byte, error)
+
+// HTTPFetcher fetches the JSON Web Key Set document at the url.
+func HTTPFetcher(client *http.Client, url string) FetchFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+		}
+
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+}
+
+// RemoteOptions represent optional parameters.
+type RemoteOptions struct {
+	refresh    time.Duration
+	minRefresh time.Duration
+}
+
+// WithRefreshInterval sets how long the fetched keys are used before they
+// are fetched again.
+func WithRefreshInterval(d time.Duration) func(opts *RemoteOptions) {
+	return func(opts *RemoteOptions) {
+		opts.refresh = d
+	}
+}
+
+// WithMinRefreshInterval sets the minimum time between two fetches caused
+// by tokens signed with an unknown key or made while the issuer is down, so
+// forged kids and retries can't make the service flood the issuer.
+func WithMinRefreshInterval(d time.Duration) func(opts *RemoteOptions) {
+	return func(opts *RemoteOptions) {
+		opts.minRefresh = d
+	}
+}
+
+// RemoteKeySet is a key set fetched from the issuer and cached. Keys the
+// issuer rotates in are fetched as soon as a token uses them. When the
+// issuer can't be reached, the keys fetched last keep being used.
+type RemoteKeySet struct {
+	fetch      FetchFunc
+	keys       *cache.Cache[string, map[string]Key]
+	refresh    time.Duration
+	minRefresh time.Duration
+
+	// fetchMu serializes the fetches made for unknown keys.
+	fetchMu sync.Mutex
+
+	mu        sync.Mutex
+	lastFetch time.Time
+	lastKeys  map[string]Key
+	lastErr   error
+}
+
+// jwksKey is the cache key of the fetched key set.
+const jwksKey = "jwks"
+
+// NewRemoteKeySet constructs a key set fetching its keys with fetch. Keys
+// are refreshed every hour and at most every minute for unknown keys by
+// default.
+func NewRemoteKeySet(fetch FetchFunc, options ...func(opts *RemoteOptions)) *RemoteKeySet {
+	opts := RemoteOptions{
+		refresh:    time.Hour,
+		minRefresh: time.Minute,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &RemoteKeySet{
+		fetch:      fetch,
+		keys:       cache.New(cache.NewMemory[string, map[string]Key](cache.WithShards[string, map[string]Key](1))),
+		refresh:    opts.refresh,
+		minRefresh: opts.minRefresh,
+	}
+}
+
+// Key implements the KeySet interface.
+func (ks *RemoteKeySet) Key(ctx context.Context, kid string) (Key, error) {
+	keys, err := ks.keys.GetOrLoad(ctx, jwksKey, ks.refresh, ks.initialLoad)
+	if err != nil {
+		stale := ks.stale()
+		if stale == nil {
+			return Key{}, err
+		}
+
+		// Keep using the stale keys and only try the issuer again once the
+		// minimum refresh interval has passed.
+		ks.keys.Set(ctx, jwksKey, stale, ks.minRefresh)
+		keys = stale
+	}
+
+	if key, exists := keys[kid]; exists {
+		return key, nil
+	}
+
+	// The issuer may have rotated its keys since the last fetch.
+	keys, err = ks.refetch(ctx)
+	if err != nil {
+		return Key{}, err
+	}
+
+	if key, exists := keys[kid]; exists {
+		return key, nil
+	}
+
+	return Key{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// refetch fetches the keys for a token signed with an unknown key, at most
+// once per minimum refresh interval. The cached keys are only replaced when
+// the fetch succeeds.
+func (ks *RemoteKeySet) refetch(ctx context.Context) (map[string]Key, error) {
+	ks.fetchMu.Lock()
+	defer ks.fetchMu.Unlock()
+
+	// Another request may have fetched the keys while this one waited.
+	if !ks.refetchAllowed() {
+		return ks.stale(), nil
+	}
+
+	keys, err := ks.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.keys.Set(ctx, jwksKey, keys, ks.refresh)
+
+	return keys, nil
+}
+
+// initialLoad loads the keys when none are cached. Until a fetch succeeds,
+// the error of the last fetch is returned until the minimum refresh interval
+// has passed, so an unreachable issuer isn't called for every token.
+func (ks *RemoteKeySet) initialLoad(ctx context.Context) (map[string]Key, error) {
+	ks.fetchMu.Lock()
+	defer ks.fetchMu.Unlock()
+
+	if err := ks.failed(); err != nil && !ks.refetchAllowed() {
+		return nil, err
+	}
+
+	return ks.load(ctx)
+}
+
+func (ks *RemoteKeySet) load(ctx context.Context) (map[string]Key, error) {
+	keys, err := ks.fetchKeys(ctx)
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.lastFetch = time.Now()
+	if err != nil {
+		ks.lastErr = err
+		return nil, err
+	}
+	ks.lastKeys = keys
+	ks.lastErr = nil
+
+	return keys, nil
+}
+
+func (ks *RemoteKeySet) fetchKeys(ctx context.Context) (map[string]Key, error) {
+	data, err := ks.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: fetch: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
+
+// failed returns the error of the last fetch when no fetch has succeeded.
+func (ks *RemoteKeySet) failed() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.lastKeys != nil {
+		return nil
+	}
+
+	return ks.lastErr
+}
+
+// stale returns the keys of the last successful fetch.
+func (ks *RemoteKeySet) stale() map[string]Key {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	return ks.lastKeys
+}
+
+func (ks *RemoteKeySet) refetchAllowed() bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	return time.Since(ks.lastFetch) >= ks.minRefresh
+}
+
+// =============================================================================
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// ParseJWKS parses the public keys of a JSON Web Key Set document. Keys
+// that aren't for signatures or of an unsupported type are skipped, and
+// symmetric keys are never accepted from a document.
+func ParseJWKS(data []byte) (map[string]Key, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jwks: decode: %w", err)
+	}
+
+	keys := make(map[string]Key, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[k.KeyID] = Key{ID: k.KeyID, Algorithm: k.Algorithm, Key: pub}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks: no usable key")
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("jwks: invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("jwks: unsupported curve %q", k.Curve)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, errors.New("jwks: invalid P-256 key")
+		}
+
+		// The point is validated by the ecdh package before it's used.
+		point := make([]byte, 65)
+		point[0] = 4
+		x.FillBytes(point[1:33])
+		y.FillBytes(point[33:])
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errors.New("jwks: point not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("jwks: unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("jwks: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("jwks: unsupported key type %q", k.KeyType)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("jwks: invalid integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// issuer serves a JSON Web Key Set document that tests can rotate or make
+// unavailable. Setting no kids keeps the current keys.
+type issuer struct {
+	mu      sync.Mutex
+	keys    map[string]ed25519.PublicKey
+	err     error
+	fetches int
+}
+
+func (is *issuer) fetch(ctx context.Context) ([]byte, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	is.fetches++
+	if is.err != nil {
+		return nil, is.err
+	}
+
+	type jwk struct {
+		KeyType string `json:"kty"`
+		Curve   string `json:"crv"`
+		KeyID   string `json:"kid"`
+		X       string `json:"x"`
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, pub := range is.keys {
+		doc.Keys = append(doc.Keys, jwk{KeyType: "OKP", Curve: "Ed25519", KeyID: kid, X: base64.RawURLEncoding.EncodeToString(pub)})
+	}
+
+	return json.Marshal(doc)
+}
+
+func (is *issuer) set(kids []string, err error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if kids != nil {
+		is.keys = make(map[string]ed25519.PublicKey)
+		for _, kid := range kids {
+			pub, _, _ := ed25519.GenerateKey(rand.Reader)
+			is.keys[kid] = pub
+		}
+	}
+	is.err = err
+}
+
+func (is *issuer) count() int {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	return is.fetches
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	errDown := errors.New("issuer down")
+
+	type step struct {
+		kids    []string
+		err     error
+		wait    time.Duration
+		kid     string
+		wantErr error
+		fetches int
+	}
+
+	tests := []struct {
+		name    string
+		options []func(opts *RemoteOptions)
+		steps   []step
+	}{
+		{
+			name:    "rotation",
+			options: []func(opts *RemoteOptions){WithMinRefreshInterval(0)},
+			steps: []step{
+				{kids: []string{"k1"}, kid: "k1", fetches: 1},
+				{kids: []string{"k1", "k2"}, kid: "k2", fetches: 2},
+				{kids: []string{"k1", "k2"}, kid: "k1", fetches: 2},
+			},
+		},
+		{
+			name:    "unknown keys are fetched once per interval",
+			options: []func(opts *RemoteOptions){WithMinRefreshInterval(time.Hour)},
+			steps: []step{
+				{kids: []string{"k1"}, kid: "forged", wantErr: ErrUnknownKey, fetches: 1},
+				{kids: []string{"k1"}, kid: "forged", wantErr: ErrUnknownKey, fetches: 1},
+				{kids: []string{"k1"}, kid: "k1", fetches: 1},
+			},
+		},
+		{
+			name: "stale keys while the issuer is down",
+			options: []func(opts *RemoteOptions){
+				WithRefreshInterval(time.Millisecond),
+				WithMinRefreshInterval(time.Hour),
+			},
+			steps: []step{
+				{kids: []string{"k1"}, kid: "k1", fetches: 1},
+				{err: errDown, wait: 5 * time.Millisecond, kid: "k1", fetches: 2},
+				{err: errDown, kid: "k1", fetches: 2},
+			},
+		},
+		{
+			name:    "never fetched",
+			options: []func(opts *RemoteOptions){WithMinRefreshInterval(0)},
+			steps: []step{
+				{err: errDown, kid: "k1", wantErr: errDown, fetches: 1},
+			},
+		},
+		{
+			name: "down since startup",
+			options: []func(opts *RemoteOptions){
+				WithRefreshInterval(time.Hour),
+				WithMinRefreshInterval(20 * time.Millisecond),
+			},
+			steps: []step{
+				{err: errDown, kid: "k1", wantErr: errDown, fetches: 1},
+				{err: errDown, kid: "k1", wantErr: errDown, fetches: 1},
+				{kids: []string{"k1"}, kid: "k1", wantErr: errDown, fetches: 1},
+				{kids: []string{"k1"}, wait: 30 * time.Millisecond, kid: "k1", fetches: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var is issuer
+			ks := NewRemoteKeySet(is.fetch, tt.options...)
+
+			for i, s := range tt.steps {
+				is.set(s.kids, s.err)
+				time.Sleep(s.wait)
+
+				key, err := ks.Key(context.Background(), s.kid)
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: got error %v, want %v", i, err, s.wantErr)
+				}
+				if err == nil && key.ID != s.kid {
+					t.Errorf("step %d: got key %q, want %q", i, key.ID, s.kid)
+				}
+				if got := is.count(); got != s.fetches {
+					t.Errorf("step %d: got %d fetches, want %d", i, got, s.fetches)
+				}
+			}
+		})
+	}
+}
@@ -11,21 +11,70 @@ import (
 	"time"
 )
 
+// ContextFn returns key/value pairs to add to every record logged with the
+// context, like the trace of the request being handled.
+type ContextFn func(ctx context.Context) []any
+
+type ctxKey int
+
+const requestIDKey ctxKey = 1
+
+// SetRequestID returns a copy of the context holding the ID of the request
+// being handled so every record logged with it contains the ID.
+func SetRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// WithRequestIDFunc returns a copy of the context where the ID of the
+// request is read from fn. It's meant for frameworks that learn the ID after
+// the contexts of the request have been derived.
+func WithRequestIDFunc(ctx context.Context, fn func() string) context.Context {
+	return context.WithValue(ctx, requestIDKey, fn)
+}
+
+// RequestID returns the ID stored with SetRequestID or WithRequestIDFunc.
+func RequestID(ctx context.Context) string {
+	switch v := ctx.Value(requestIDKey).(type) {
+	case string:
+		return v
+	case func() string:
+		return v()
+	}
+
+	return ""
+}
+
+// logRequestID is added to the context functions of every logger.
+func logRequestID(ctx context.Context) []any {
+	if id := RequestID(ctx); id != "" {
+		return []any{"request_id", id}
+	}
+
+	return nil
+}
+
 // Logger represents a logger for logging information.
 type Logger struct {
 	discard bool
 	handler slog.Handler
+	ctxFns  []ContextFn
 }
 
-// New constructs a new log for application use.
-func New(w io.Writer, minLevel Level, serviceName string) *Logger {
-	return new(w, minLevel, serviceName, Events{})
+// New constructs a new log for application use. Records contain the ID of
+// the request stored in the context with SetRequestID, followed by the
+// key/value pairs of the context functions.
+func New(w io.Writer, minLevel Level, serviceName string, ctxFns ...ContextFn) *Logger {
+	return new(w, minLevel, serviceName, Events{}, ctxFns)
 }
 
 // NewWithHandler returns a new log for application use with the underlying
-// handler.
-func NewWithHandler(h slog.Handler) *Logger {
-	return &Logger{handler: h}
+// handler. Like New, records contain the ID of the request and the key/value
+// pairs of the context functions.
+func NewWithHandler(h slog.Handler, ctxFns ...ContextFn) *Logger {
+	return &Logger{
+		handler: h,
+		ctxFns:  append([]ContextFn{logRequestID}, ctxFns...),
+	}
 }
 
 // NewStdLogger returns a standard library Logger that wraps the slog Logger.
@@ -119,10 +168,14 @@ func (log *Logger) write(ctx context.Context, level Level, caller int, msg strin
 
 	r.Add(args...)
 
+	for _, fn := range log.ctxFns {
+		r.Add(fn(ctx)...)
+	}
+
 	log.handler.Handle(ctx, r)
 }
 
-func new(w io.Writer, minLevel Level, serviceName string, events Events) *Logger {
+func new(w io.Writer, minLevel Level, serviceName string, events Events, ctxFns []ContextFn) *Logger {
 
 	// Convert the file name to just the name.ext when this key/value will
 	// be logged.
@@ -159,5 +212,6 @@ func new(w io.Writer, minLevel Level, serviceName string, events Events) *Logger
 	return &Logger{
 		discard: w == io.Discard,
 		handler: handler,
+		ctxFns:  append([]ContextFn{logRequestID}, ctxFns...),
 	}
 }
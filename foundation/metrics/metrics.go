@@ -0,0 +1,200 @@
+// Package metrics provides counters, gauges and histograms exposed in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metric is implemented by everything the registry can expose.
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metrics of a service.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry constructs an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+// NewCounter registers a counter. Calling it again with the same name
+// returns the registered counter.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return register(r, name, func() *Counter {
+		return &Counter{vec: newVec[float64](name, help, labels)}
+	})
+}
+
+// NewGauge registers a gauge. Calling it again with the same name returns
+// the registered gauge.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return register(r, name, func() *Gauge {
+		return &Gauge{vec: newVec[float64](name, help, labels)}
+	})
+}
+
+// NewHistogram registers a histogram with the upper bounds of its buckets.
+// DefaultBuckets are used when no bucket is provided. Calling it again with
+// the same name returns the registered histogram.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return register(r, name, func() *Histogram {
+		return &Histogram{vec: newVec[*histogramValue](name, help, labels), buckets: buckets}
+	})
+}
+
+// register adds the metric built by fn unless a metric with the same name
+// exists. Registering the same name for another type is a programming error.
+func register[M metric](r *Registry, name string, fn func() M) M {
+	if !validName(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, exists := r.metrics[name]; exists {
+		v, ok := m.(M)
+		if !ok {
+			panic(fmt.Sprintf("metrics: metric %q already registered with another type", name))
+		}
+		return v
+	}
+
+	m := fn()
+	r.metrics[name] = m
+	return m
+}
+
+// WriteTo writes every metric in the text exposition format, sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(metrics, func(a, b metric) int {
+		return strings.Compare(a.name(), b.name())
+	})
+
+	cw := countWriter{w: w}
+	bw := bufio.NewWriter(&cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler returns a handler exposing the metrics of the registry. It is
+// meant to be mounted on an admin port rather than on the public API.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// =============================================================================
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, helpReplacer.Replace(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extra string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extra != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, labelReplacer.Replace(values[i]))
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(r *Registry)
+		want   string
+	}{
+		{
+			name: "counter",
+			record: func(r *Registry) {
+				c := r.NewCounter("http_requests_total", "Requests served.", "method", "status")
+				c.Inc("GET", "200")
+				c.Add(2, "GET", "200")
+				c.Inc("POST", "500")
+			},
+			want: `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",status="200"} 3
+http_requests_total{method="POST",status="500"} 1
+`,
+		},
+		{
+			name: "gauge",
+			record: func(r *Registry) {
+				g := r.NewGauge("in_flight", "")
+				g.Inc()
+				g.Inc()
+				g.Dec()
+				g.Add(0.5)
+			},
+			want: `# TYPE in_flight gauge
+in_flight 1.5
+`,
+		},
+		{
+			name: "histogram",
+			record: func(r *Registry) {
+				h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "route")
+				h.Observe(0.05, "/a")
+				h.Observe(0.1, "/a")
+				h.Observe(0.5, "/a")
+				h.Observe(2, "/a")
+			},
+			want: `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 2.65
+latency_seconds_count{route="/a"} 4
+`,
+		},
+		{
+			name: "escaping",
+			record: func(r *Registry) {
+				c := r.NewCounter("errors_total", "Errors\nwith a \\ backslash.", "message")
+				c.Inc("say \"hi\"\n")
+			},
+			want: `# HELP errors_total Errors\nwith a \\ backslash.
+# TYPE errors_total counter
+errors_total{message="say \"hi\"\n"} 1
+`,
+		},
+		{
+			name: "sorted by name",
+			record: func(r *Registry) {
+				r.NewGauge("b", "").Set(2)
+				r.NewGauge("a", "").Set(1)
+			},
+			want: `# TYPE a gauge
+a 1
+# TYPE b gauge
+b 2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.record(r)
+
+			var b strings.Builder
+			n, err := r.WriteTo(&b)
+			if err != nil {
+				t.Fatalf("write: %s", err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if n != int64(b.Len()) {
+				t.Errorf("got %d bytes written, want %d", n, b.Len())
+			}
+		})
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Registry)
+	}{
+		{name: "invalid name", register: func(r *Registry) { r.NewCounter("1st", "") }},
+		{name: "reserved label", register: func(r *Registry) { r.NewHistogram("h", "", nil, "le") }},
+		{name: "another type", register: func(r *Registry) {
+			r.NewCounter("m", "")
+			r.NewGauge("m", "")
+		}},
+		{name: "label values", register: func(r *Registry) { r.NewCounter("c", "", "a").Inc() }},
+		{name: "negative counter", register: func(r *Registry) { r.NewCounter("c", "").Add(-1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("got no panic, want a panic")
+				}
+			}()
+
+			tt.register(NewRegistry())
+		})
+	}
+}
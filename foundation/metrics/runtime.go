@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bufio"
+	"runtime"
+	"time"
+)
+
+// RegisterRuntime registers the statistics of the Go runtime. The runtime
+// is read once per exposition.
+func (r *Registry) RegisterRuntime() {
+	register(r, "go_runtime", func() *runtimeCollector {
+		return &runtimeCollector{start: time.Now()}
+	})
+}
+
+type runtimeCollector struct {
+	start time.Time
+}
+
+func (rc *runtimeCollector) name() string {
+	return "go_runtime"
+}
+
+func (rc *runtimeCollector) write(w *bufio.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gauge := func(name, help string, v float64) {
+		writeHeader(w, name, help, "gauge")
+		writeSample(w, name, nil, nil, "", v)
+	}
+
+	counter := func(name, help string, v float64) {
+		writeHeader(w, name, help, "counter")
+		writeSample(w, name, nil, nil, "", v)
+	}
+
+	gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	gauge("go_gomaxprocs", "Value of GOMAXPROCS.", float64(runtime.GOMAXPROCS(0)))
+	gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc))
+	counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(ms.TotalAlloc))
+	gauge("go_memstats_sys_bytes", "Number of bytes obtained from the system.", float64(ms.Sys))
+	gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects))
+	gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse))
+	gauge("go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.", float64(ms.StackInuse))
+	counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC))
+	counter("go_gc_pause_seconds_total", "Total time the GC stopped the world.", time.Duration(ms.PauseTotalNs).Seconds())
+	gauge("go_memstats_next_gc_bytes", "Heap size at which the next GC cycle starts.", float64(ms.NextGC))
+	gauge("process_start_time_seconds", "Start time of the process since the unix epoch in seconds.", float64(rc.start.Unix()))
+}
@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds suited to most APIs.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// series is the value of a metric for one set of label values.
+type series[T any] struct {
+	values []string
+	value  T
+}
+
+// vec holds the series of a metric keyed by their label values.
+type vec[T any] struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*series[T]
+}
+
+func newVec[T any](name, help string, labels []string) vec[T] {
+	for _, label := range labels {
+		if !validName(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %q", label, name))
+		}
+	}
+
+	return vec[T]{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		series:     make(map[string]*series[T]),
+	}
+}
+
+func (v *vec[T]) name() string {
+	return v.metricName
+}
+
+// update calls fn with the series of the label values under the lock. A
+// wrong number of label values is a programming error.
+func (v *vec[T]) update(values []string, create func() T, fn func(s *series[T])) {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %q expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, exists := v.series[key]
+	if !exists {
+		s = &series[T]{values: slices.Clone(values), value: create()}
+		v.series[key] = s
+	}
+
+	fn(s)
+}
+
+// sorted returns the series ordered by label values for a stable output.
+func (v *vec[T]) sorted() []*series[T] {
+	list := make([]*series[T], 0, len(v.series))
+	for _, s := range v.series {
+		list = append(list, s)
+	}
+
+	slices.SortFunc(list, func(a, b *series[T]) int {
+		return slices.Compare(a.values, b.values)
+	})
+
+	return list
+}
+
+func zero() float64 { return 0 }
+
+// =============================================================================
+
+// Counter is a value that only goes up, like the number of requests.
+type Counter struct {
+	vec[float64]
+}
+
+// Inc adds one to the series of the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds a positive delta to the series of the label values.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %q cannot decrease", c.metricName))
+	}
+
+	c.update(values, zero, func(s *series[float64]) {
+		s.value += delta
+	})
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.metricName, c.help, "counter")
+	for _, s := range c.sorted() {
+		writeSample(w, c.metricName, c.labels, s.values, "", s.value)
+	}
+}
+
+// =============================================================================
+
+// Gauge is a value that can go up and down, like the requests in flight.
+type Gauge struct {
+	vec[float64]
+}
+
+// Set sets the series of the label values.
+func (g *Gauge) Set(v float64, values ...string) {
+	g.update(values, zero, func(s *series[float64]) {
+		s.value = v
+	})
+}
+
+// Add adds the delta, which can be negative, to the series of the label
+// values.
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.update(values, zero, func(s *series[float64]) {
+		s.value += delta
+	})
+}
+
+// Inc adds one to the series of the label values.
+func (g *Gauge) Inc(values ...string) {
+	g.Add(1, values...)
+}
+
+// Dec subtracts one from the series of the label values.
+func (g *Gauge) Dec(values ...string) {
+	g.Add(-1, values...)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	writeHeader(w, g.metricName, g.help, "gauge")
+	for _, s := range g.sorted() {
+		writeSample(w, g.metricName, g.labels, s.values, "", s.value)
+	}
+}
+
+// =============================================================================
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram counts observations, like request latencies, in buckets.
+type Histogram struct {
+	vec[*histogramValue]
+	buckets []float64
+}
+
+// Observe adds the value to the series of the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	create := func() *histogramValue {
+		return &histogramValue{counts: make([]uint64, len(h.buckets))}
+	}
+
+	h.update(values, create, func(s *series[*histogramValue]) {
+		if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+			s.value.counts[i]++
+		}
+		s.value.count++
+		s.value.sum += v
+	})
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.metricName, h.help, "histogram")
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.value.counts[i]
+			le := fmt.Sprintf("le=\"%s\"", formatFloat(bound))
+			writeSample(w, h.metricName+"_bucket", h.labels, s.values, le, float64(cumulative))
+		}
+
+		le := fmt.Sprintf("le=\"%s\"", formatFloat(math.Inf(1)))
+		writeSample(w, h.metricName+"_bucket", h.labels, s.values, le, float64(s.value.count))
+		writeSample(w, h.metricName+"_sum", h.labels, s.values, "", s.value.sum)
+		writeSample(w, h.metricName+"_count", h.labels, s.values, "", float64(s.value.count))
+	}
+}
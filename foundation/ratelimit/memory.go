@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryOptions represent optional parameters.
+type MemoryOptions struct {
+	maxKeys     int
+	idleTimeout time.Duration
+	now         func() time.Time
+}
+
+// WithMaxKeys bounds the number of keys kept in memory. The least recently
+// used key is evicted when the bound is reached.
+func WithMaxKeys(n int) func(opts *MemoryOptions) {
+	return func(opts *MemoryOptions) {
+		opts.maxKeys = n
+	}
+}
+
+// WithIdleTimeout sets how long a key is kept without requests.
+func WithIdleTimeout(d time.Duration) func(opts *MemoryOptions) {
+	return func(opts *MemoryOptions) {
+		opts.idleTimeout = d
+	}
+}
+
+// WithClock replaces the clock of the store.
+func WithClock(now func() time.Time) func(opts *MemoryOptions) {
+	return func(opts *MemoryOptions) {
+		opts.now = now
+	}
+}
+
+// MemoryStore keeps the state of the keys in the memory of the process.
+type MemoryStore struct {
+	maxKeys     int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu    sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key      string
+	lastSeen time.Time
+	state    any
+}
+
+// NewMemoryStore constructs a store keeping up to 100,000 keys idle for at
+// most an hour by default.
+func NewMemoryStore(options ...func(opts *MemoryOptions)) *MemoryStore {
+	opts := MemoryOptions{
+		maxKeys:     100_000,
+		idleTimeout: time.Hour,
+		now:         time.Now,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &MemoryStore{
+		maxKeys:     opts.maxKeys,
+		idleTimeout: opts.idleTimeout,
+		now:         opts.now,
+		lru:         list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+// Take implements the Store interface.
+func (s *MemoryStore) Take(ctx context.Context, key string, quota Quota) (Result, error) {
+	if err := quota.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictIdle(now)
+
+	e := s.entry(key, now)
+	e.lastSeen = now
+
+	switch quota.Algorithm {
+	case SlidingWindow:
+		state, ok := e.state.(*slidingWindow)
+		if !ok {
+			state = &slidingWindow{start: now.Truncate(quota.Period)}
+			e.state = state
+		}
+		return state.take(now, quota), nil
+
+	default:
+		state, ok := e.state.(*tokenBucket)
+		if !ok {
+			state = &tokenBucket{tokens: float64(quota.burst()), last: now}
+			e.state = state
+		}
+		return state.take(now, quota), nil
+	}
+}
+
+// Len returns the number of keys in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+// entry returns the entry of the key as the most recently used one,
+// evicting the least recently used entry when the store is full.
+func (s *MemoryStore) entry(key string, now time.Time) *entry {
+	if elem, exists := s.items[key]; exists {
+		s.lru.MoveToFront(elem)
+		return elem.Value.(*entry)
+	}
+
+	if s.maxKeys > 0 && s.lru.Len() >= s.maxKeys {
+		s.remove(s.lru.Back())
+	}
+
+	e := entry{key: key, lastSeen: now}
+	s.items[key] = s.lru.PushFront(&e)
+
+	return &e
+}
+
+// evictIdle removes the keys without requests for longer than the idle
+// timeout, starting with the least recently used one.
+func (s *MemoryStore) evictIdle(now time.Time) {
+	if s.idleTimeout <= 0 {
+		return
+	}
+
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if now.Sub(elem.Value.(*entry).lastSeen) < s.idleTimeout {
+			return
+		}
+		s.remove(elem)
+	}
+}
+
+func (s *MemoryStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.items, elem.Value.(*entry).key)
+}
+
+// =============================================================================
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time, quota Quota) Result {
+	capacity := float64(quota.burst())
+	perSecond := float64(quota.Limit) / quota.Period.Seconds()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*perSecond)
+	}
+	b.last = now
+
+	res := Result{Limit: quota.burst()}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / perSecond)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = seconds((capacity - b.tokens) / perSecond)
+
+	return res
+}
+
+// =============================================================================
+
+type slidingWindow struct {
+	start    time.Time
+	previous int
+	current  int
+}
+
+func (w *slidingWindow) take(now time.Time, quota Quota) Result {
+	period := quota.Period
+
+	if elapsed := now.Sub(w.start); elapsed >= period {
+		w.previous = 0
+		if elapsed < 2*period {
+			w.previous = w.current
+		}
+		w.current = 0
+		w.start = now.Truncate(period)
+	}
+
+	elapsed := now.Sub(w.start)
+	weight := 1 - float64(elapsed)/float64(period)
+	estimate := float64(w.previous)*weight + float64(w.current)
+
+	res := Result{
+		Limit:      quota.Limit,
+		ResetAfter: period - elapsed,
+	}
+
+	if estimate+1 <= float64(quota.Limit) {
+		w.current++
+		res.Allowed = true
+		res.Remaining = quota.Limit - int(math.Ceil(estimate+1))
+		return res
+	}
+
+	// The estimate drops as the previous window slides out. When the
+	// current window alone exceeds the limit the client must wait for the
+	// next window.
+	res.RetryAfter = period - elapsed
+	if w.previous > 0 && w.current < quota.Limit {
+		room := float64(quota.Limit-1-w.current) / float64(w.previous)
+		res.RetryAfter = time.Duration((1-room)*float64(period)) - elapsed
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	type step struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		quota Quota
+		limit int
+		steps []step
+	}{
+		{
+			name:  "token bucket",
+			quota: Quota{Limit: 2, Period: time.Second},
+			limit: 2,
+			steps: []step{
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+				{advance: 500 * time.Millisecond, allowed: true, remaining: 0},
+				{advance: time.Hour, allowed: true, remaining: 1},
+			},
+		},
+		{
+			name:  "token bucket burst",
+			quota: Quota{Limit: 1, Period: time.Second, Burst: 3},
+			limit: 3,
+			steps: []step{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retryAfter: time.Second},
+				{advance: time.Second, allowed: true, remaining: 0},
+			},
+		},
+		{
+			name:  "sliding window",
+			quota: Quota{Limit: 2, Period: time.Minute, Algorithm: SlidingWindow},
+			limit: 2,
+			steps: []step{
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, retryAfter: time.Minute},
+				{advance: time.Minute, allowed: false, retryAfter: 30 * time.Second},
+				{advance: 30 * time.Second, allowed: true, remaining: 0},
+				{advance: 2 * time.Minute, allowed: true, remaining: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock{now: time.Unix(0, 0)}
+			store := NewMemoryStore(WithClock(clk.Now))
+
+			for i, s := range tt.steps {
+				clk.Advance(s.advance)
+
+				res, err := store.Take(context.Background(), "key", tt.quota)
+				if err != nil {
+					t.Fatalf("step %d: take: %s", i, err)
+				}
+
+				if res.Allowed != s.allowed {
+					t.Errorf("step %d: got allowed %t, want %t", i, res.Allowed, s.allowed)
+				}
+				if res.Remaining != s.remaining {
+					t.Errorf("step %d: got remaining %d, want %d", i, res.Remaining, s.remaining)
+				}
+				if res.RetryAfter != s.retryAfter {
+					t.Errorf("step %d: got retry after %s, want %s", i, res.RetryAfter, s.retryAfter)
+				}
+				if res.Limit != tt.limit {
+					t.Errorf("step %d: got limit %d, want %d", i, res.Limit, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		key     string
+		allowed bool
+	}
+
+	tests := []struct {
+		name    string
+		options []func(opts *MemoryOptions)
+		steps   []step
+		len     int
+	}{
+		{
+			name:    "least recently used",
+			options: []func(opts *MemoryOptions){WithMaxKeys(2)},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "b", allowed: true},
+				{key: "a", allowed: false},
+				{key: "c", allowed: true},
+				{key: "b", allowed: true},
+				{key: "c", allowed: false},
+			},
+			len: 2,
+		},
+		{
+			name:    "idle",
+			options: []func(opts *MemoryOptions){WithIdleTimeout(time.Minute)},
+			steps: []step{
+				{key: "a", allowed: true},
+				{advance: 30 * time.Second, key: "b", allowed: true},
+				{advance: 30 * time.Second, key: "b", allowed: false},
+				{key: "a", allowed: true},
+			},
+			len: 2,
+		},
+		{
+			name:    "no idle timeout",
+			options: []func(opts *MemoryOptions){WithIdleTimeout(0)},
+			steps: []step{
+				{key: "a", allowed: true},
+				{advance: 24 * time.Hour, key: "a", allowed: false},
+			},
+			len: 1,
+		},
+	}
+
+	// One request per year, so only a removed key is allowed again.
+	quota := Quota{Limit: 1, Period: 365 * 24 * time.Hour}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock{now: time.Unix(0, 0)}
+			store := NewMemoryStore(append(tt.options, WithClock(clk.Now))...)
+
+			for i, s := range tt.steps {
+				clk.Advance(s.advance)
+
+				res, err := store.Take(context.Background(), s.key, quota)
+				if err != nil {
+					t.Fatalf("step %d: take: %s", i, err)
+				}
+				if res.Allowed != s.allowed {
+					t.Errorf("step %d: got allowed %t for %s, want %t", i, res.Allowed, s.key, s.allowed)
+				}
+			}
+
+			if got := store.Len(); got != tt.len {
+				t.Errorf("got %d keys, want %d", got, tt.len)
+			}
+		})
+	}
+}
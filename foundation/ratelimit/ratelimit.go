@@ -0,0 +1,95 @@
+// Package ratelimit provides keyed rate limiting with pluggable algorithms
+// and stores.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Algorithm selects how the requests of a key are counted.
+type Algorithm int
+
+// Set of algorithms.
+const (
+	// TokenBucket refills Limit tokens per Period up to Burst tokens, which
+	// allows short bursts while enforcing the average rate.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow allows Limit requests in any window of Period, weighting
+	// the previous window to avoid the bursts of fixed windows.
+	SlidingWindow
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindow:
+		return "sliding_window"
+	}
+
+	return fmt.Sprintf("algorithm(%d)", int(a))
+}
+
+// Quota is the number of requests allowed for a key in a period.
+type Quota struct {
+	Limit     int
+	Period    time.Duration
+	Burst     int
+	Algorithm Algorithm
+}
+
+// PerSecond returns a token bucket quota of n requests per second.
+func PerSecond(n int) Quota {
+	return Quota{Limit: n, Period: time.Second}
+}
+
+// PerMinute returns a token bucket quota of n requests per minute.
+func PerMinute(n int) Quota {
+	return Quota{Limit: n, Period: time.Minute}
+}
+
+// burst returns the capacity of a token bucket, which defaults to Limit.
+func (q Quota) burst() int {
+	if q.Burst > 0 {
+		return q.Burst
+	}
+	return q.Limit
+}
+
+// Validate reports if the quota can be enforced.
+func (q Quota) Validate() error {
+	if q.Limit <= 0 {
+		return fmt.Errorf("ratelimit: limit must be positive, got %d", q.Limit)
+	}
+
+	if q.Period <= 0 {
+		return fmt.Errorf("ratelimit: period must be positive, got %s", q.Period)
+	}
+
+	return nil
+}
+
+// Policy formats the RateLimit-Policy header for limit requests per period.
+// Pass the Limit of the Result so the policy matches the RateLimit-Limit and
+// RateLimit-Remaining headers, since token buckets report their burst.
+func Policy(limit int, period time.Duration) string {
+	return fmt.Sprintf("%d;w=%d", limit, int((period+time.Second-1)/time.Second))
+}
+
+// Result is the decision for a request.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of the keys and decides if a request is allowed.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, quota Quota) (Result, error)
+}
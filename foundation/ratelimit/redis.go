@@ -0,0 +1,257 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/resp"
+)
+
+// gcraScript implements the generic cell rate algorithm atomically in
+// Redis. The state of a key is its theoretical arrival time (TAT) in
+// milliseconds of the clock of Redis, so replicas don't need synchronized
+// clocks. It returns the decision, the remaining requests, the reset and
+// the retry delays in milliseconds.
+//
+// The tests run gcra, its Go copy, on the stand-in server since it can't run
+// Lua. Changes to the script must be checked against a real Redis and kept
+// in sync with gcra.
+const gcraScript = `local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+  tat = now
+end
+local tolerance = emission * burst
+local new_tat = tat + emission
+local diff = now - (new_tat - tolerance)
+if diff < 0 then
+  return {0, 0, math.ceil(tat - now), math.ceil(-diff)}
+end
+redis.call('SET', KEYS[1], string.format('%.3f', new_tat), 'PX', math.ceil(new_tat - now))
+return {1, math.floor(diff / emission), math.ceil(new_tat - now), 0}
+`
+
+// RegisterScripts registers the Go equivalent of the scripts of the Redis
+// store on the stand-in server.
+func RegisterScripts(srv *resp.Server) {
+	srv.RegisterScript(gcraScript, gcra)
+}
+
+// gcra is the Go equivalent of gcraScript.
+func gcra(db *resp.DB, keys []string, args []string) (any, error) {
+	if len(keys) != 1 || len(args) != 2 {
+		return nil, fmt.Errorf("gcra: expects 1 key and 2 arguments")
+	}
+
+	emission, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("gcra: emission: %w", err)
+	}
+
+	burst, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("gcra: burst: %w", err)
+	}
+
+	now := float64(db.Now().UnixMicro() / 1000)
+
+	tat := now
+	if v, ok := db.Get(keys[0]); ok {
+		if stored, err := strconv.ParseFloat(v, 64); err == nil && stored > now {
+			tat = stored
+		}
+	}
+
+	tolerance := emission * burst
+	newTAT := tat + emission
+	diff := now - (newTAT - tolerance)
+	if diff < 0 {
+		return []any{int64(0), int64(0), int64(math.Ceil(tat - now)), int64(math.Ceil(-diff))}, nil
+	}
+
+	ttl := time.Duration(math.Ceil(newTAT-now)) * time.Millisecond
+	db.Set(keys[0], strconv.FormatFloat(newTAT, 'f', 3, 64), ttl)
+
+	return []any{int64(1), int64(math.Floor(diff / emission)), int64(math.Ceil(newTAT - now)), int64(0)}, nil
+}
+
+// =============================================================================
+
+// RedisOptions represent optional parameters.
+type RedisOptions struct {
+	prefix        string
+	fallback      Store
+	probeInterval time.Duration
+	onError       func(err error)
+}
+
+// WithKeyPrefix sets the prefix of the keys written to Redis.
+func WithKeyPrefix(prefix string) func(opts *RedisOptions) {
+	return func(opts *RedisOptions) {
+		opts.prefix = prefix
+	}
+}
+
+// WithFallback sets the store used while Redis is unreachable. It defaults
+// to a memory store, so each replica enforces the quota on its own.
+func WithFallback(store Store) func(opts *RedisOptions) {
+	return func(opts *RedisOptions) {
+		opts.fallback = store
+	}
+}
+
+// WithProbeInterval sets how long the fallback is used before Redis is
+// tried again, so requests don't wait for an unreachable server.
+func WithProbeInterval(d time.Duration) func(opts *RedisOptions) {
+	return func(opts *RedisOptions) {
+		opts.probeInterval = d
+	}
+}
+
+// WithStoreErrorHandler sets the function called when Redis fails and the
+// fallback is used.
+func WithStoreErrorHandler(fn func(err error)) func(opts *RedisOptions) {
+	return func(opts *RedisOptions) {
+		opts.onError = fn
+	}
+}
+
+// RedisStore shares the state of the keys between replicas in Redis using
+// GCRA, which is equivalent to a token bucket, whatever the algorithm of the
+// quota.
+type RedisStore struct {
+	client        *resp.Client
+	sha           string
+	prefix        string
+	fallback      Store
+	probeInterval time.Duration
+	onError       func(err error)
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// NewRedisStore constructs a store using the client.
+func NewRedisStore(client *resp.Client, options ...func(opts *RedisOptions)) *RedisStore {
+	opts := RedisOptions{
+		prefix:        "ratelimit:",
+		probeInterval: 5 * time.Second,
+		onError:       func(error) {},
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if opts.fallback == nil {
+		opts.fallback = NewMemoryStore()
+	}
+
+	return &RedisStore{
+		client:        client,
+		sha:           resp.ScriptSHA(gcraScript),
+		prefix:        opts.prefix,
+		fallback:      opts.fallback,
+		probeInterval: opts.probeInterval,
+		onError:       opts.onError,
+	}
+}
+
+// Take implements the Store interface. The fallback store decides while
+// Redis is unreachable.
+func (s *RedisStore) Take(ctx context.Context, key string, quota Quota) (Result, error) {
+	if err := quota.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	if s.down() {
+		return s.fallback.Take(ctx, key, quota)
+	}
+
+	res, err := s.take(ctx, key, quota)
+	if err != nil {
+		// Error replies and requests that gave up don't say anything about
+		// the health of Redis, so only dial, I/O and protocol failures
+		// switch to the fallback.
+		if resp.IsServerError(err) || ctx.Err() != nil || isContextError(err) {
+			return Result{}, err
+		}
+
+		s.markDown()
+		s.onError(err)
+		return s.fallback.Take(ctx, key, quota)
+	}
+
+	return res, nil
+}
+
+func (s *RedisStore) take(ctx context.Context, key string, quota Quota) (Result, error) {
+	emission := float64(quota.Period.Microseconds()) / 1000 / float64(quota.Limit)
+
+	args := []string{
+		"1",
+		s.prefix + key,
+		strconv.FormatFloat(emission, 'f', 3, 64),
+		strconv.Itoa(quota.burst()),
+	}
+
+	v, err := s.client.Do(ctx, append([]string{"EVALSHA", s.sha}, args...)...)
+	if err != nil && isNoScript(err) {
+		v, err = s.client.Do(ctx, append([]string{"EVAL", gcraScript}, args...)...)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := v.([]any)
+	if !ok || len(values) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", v)
+	}
+
+	var n [4]int64
+	for i, value := range values {
+		if n[i], err = resp.Int(value); err != nil {
+			return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", v)
+		}
+	}
+
+	res := Result{
+		Allowed:    n[0] == 1,
+		Limit:      quota.burst(),
+		Remaining:  int(n[1]),
+		ResetAfter: time.Duration(n[2]) * time.Millisecond,
+		RetryAfter: time.Duration(n[3]) * time.Millisecond,
+	}
+
+	return res, nil
+}
+
+func (s *RedisStore) down() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return time.Now().Before(s.downUntil)
+}
+
+func (s *RedisStore) markDown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.downUntil = time.Now().Add(s.probeInterval)
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func isNoScript(err error) bool {
+	return resp.IsServerError(err) && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
@@ -0,0 +1,268 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/resp"
+)
+
+// The stand-in server can't run Lua, so these tests run the store against
+// gcra, the Go copy of gcraScript. The Lua script itself is not tested here
+// and must be checked against a real Redis when it changes.
+
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+
+	srv := resp.NewServer()
+	RegisterScripts(srv)
+
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return ln.Addr().String()
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	tests := []struct {
+		name      string
+		quota     Quota
+		takes     int
+		allowed   int
+		remaining []int
+	}{
+		{
+			name:      "limit",
+			quota:     PerMinute(3),
+			takes:     4,
+			allowed:   3,
+			remaining: []int{2, 1, 0, 0},
+		},
+		{
+			name:      "burst",
+			quota:     Quota{Limit: 1, Period: time.Minute, Burst: 2},
+			takes:     3,
+			allowed:   2,
+			remaining: []int{1, 0, 0},
+		},
+	}
+
+	client := resp.NewClient(startServer(t))
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client, WithStoreErrorHandler(func(err error) {
+		t.Errorf("unexpected store error: %s", err)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var allowed int
+			for i := range tt.takes {
+				res, err := store.Take(context.Background(), tt.name, tt.quota)
+				if err != nil {
+					t.Fatalf("take %d: %s", i, err)
+				}
+
+				if res.Allowed {
+					allowed++
+				} else if res.RetryAfter <= 0 {
+					t.Errorf("take %d: got retry after %s, want positive", i, res.RetryAfter)
+				}
+
+				if res.Remaining != tt.remaining[i] {
+					t.Errorf("take %d: got remaining %d, want %d", i, res.Remaining, tt.remaining[i])
+				}
+
+				if res.Limit != tt.quota.burst() {
+					t.Errorf("take %d: got limit %d, want %d", i, res.Limit, tt.quota.burst())
+				}
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("got %d allowed, want %d", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestRedisStoreKeys(t *testing.T) {
+	client := resp.NewClient(startServer(t))
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client, WithKeyPrefix("test:"))
+	quota := PerMinute(1)
+
+	for _, key := range []string{"a", "b"} {
+		res, err := store.Take(context.Background(), key, quota)
+		if err != nil {
+			t.Fatalf("take %s: %s", key, err)
+		}
+		if !res.Allowed {
+			t.Errorf("take %s: got denied, want allowed since keys are counted separately", key)
+		}
+	}
+
+	v, err := client.Do(context.Background(), "GET", "test:a")
+	if err != nil || v == nil {
+		t.Errorf("got %v, %v for the prefixed key, want a value", v, err)
+	}
+}
+
+func TestRedisStoreFallback(t *testing.T) {
+	addr := startServer(t)
+
+	// Nothing listens on the address once the listener of a closed server
+	// is released.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name     string
+		addr     string
+		fallback bool
+	}{
+		{name: "up", addr: addr, fallback: false},
+		{name: "down", addr: down, fallback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := resp.NewClient(tt.addr, resp.WithDialTimeout(time.Second))
+			t.Cleanup(func() { client.Close() })
+
+			var storeErr error
+			fallback := NewMemoryStore()
+			store := NewRedisStore(client,
+				WithFallback(fallback),
+				WithStoreErrorHandler(func(err error) { storeErr = err }),
+			)
+
+			res, err := store.Take(context.Background(), "key", PerMinute(1))
+			if err != nil {
+				t.Fatalf("take: %s", err)
+			}
+			if !res.Allowed {
+				t.Errorf("got denied, want allowed")
+			}
+
+			if got := fallback.Len() == 1; got != tt.fallback {
+				t.Errorf("got fallback used %t, want %t", got, tt.fallback)
+			}
+			if got := storeErr != nil; got != tt.fallback {
+				t.Errorf("got store error %v, want error %t", storeErr, tt.fallback)
+			}
+		})
+	}
+}
+
+func TestRedisStoreInvalidQuota(t *testing.T) {
+	client := resp.NewClient(startServer(t))
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client)
+
+	tests := []struct {
+		name  string
+		quota Quota
+	}{
+		{name: "limit", quota: Quota{Period: time.Second}},
+		{name: "period", quota: Quota{Limit: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Take(context.Background(), "key", tt.quota)
+			if err == nil {
+				t.Fatal("got nil error, want an invalid quota error")
+			}
+
+			var opErr *net.OpError
+			if errors.As(err, &opErr) {
+				t.Errorf("got network error %s, want the quota to be rejected before calling Redis", err)
+			}
+		})
+	}
+}
+
+func TestRedisStoreContextError(t *testing.T) {
+	// The server accepts connections but never replies, so the requests
+	// only end with their context.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { c.Close() })
+		}
+	}()
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name: "canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+		},
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := resp.NewClient(ln.Addr().String(), resp.WithDialTimeout(time.Second))
+			t.Cleanup(func() { client.Close() })
+
+			var storeErr error
+			fallback := NewMemoryStore()
+			store := NewRedisStore(client,
+				WithFallback(fallback),
+				WithStoreErrorHandler(func(err error) { storeErr = err }),
+			)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			if _, err := store.Take(ctx, "key", PerMinute(1)); err == nil {
+				t.Error("got nil error, want the error of the request")
+			}
+
+			if store.down() {
+				t.Error("got store marked down, want up")
+			}
+			if fallback.Len() != 0 {
+				t.Error("got fallback used, want unused")
+			}
+			if storeErr != nil {
+				t.Errorf("got store error %s, want none", storeErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Options represent optional parameters.
+type Options struct {
+	poolSize    int
+	dialTimeout time.Duration
+	ioTimeout   time.Duration
+	password    string
+}
+
+// WithPoolSize sets the number of idle connections kept open.
+func WithPoolSize(n int) func(opts *Options) {
+	return func(opts *Options) {
+		opts.poolSize = n
+	}
+}
+
+// WithDialTimeout sets the maximum time to connect to the server.
+func WithDialTimeout(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.dialTimeout = d
+	}
+}
+
+// WithIOTimeout sets the maximum time of a command when the context has no
+// earlier deadline.
+func WithIOTimeout(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.ioTimeout = d
+	}
+}
+
+// WithPassword authenticates every new connection.
+func WithPassword(password string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.password = password
+	}
+}
+
+// Client sends commands to a RESP server over a pool of connections. It is
+// safe for concurrent use.
+type Client struct {
+	addr        string
+	dialTimeout time.Duration
+	ioTimeout   time.Duration
+	password    string
+	pool        chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewClient constructs a client for the server at addr. Connections are
+// opened when needed.
+func NewClient(addr string, options ...func(opts *Options)) *Client {
+	opts := Options{
+		poolSize:    10,
+		dialTimeout: time.Second,
+		ioTimeout:   time.Second,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Client{
+		addr:        addr,
+		dialTimeout: opts.dialTimeout,
+		ioTimeout:   opts.ioTimeout,
+		password:    opts.password,
+		pool:        make(chan *conn, opts.poolSize),
+	}
+}
+
+// Do sends the command and returns its reply. An error reply is returned as
+// an Error. A timeout caused by the deadline of the context matches
+// context.DeadlineExceeded, any other error means the server couldn't be
+// reached.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, pooled, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := c.roundTrip(ctx, cn, args)
+	if err != nil && pooled && isStale(err) {
+		// The server may have closed an idle connection, like after a
+		// restart, so the command is retried once on a new connection.
+		cn.Close()
+		if cn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+		v, err = c.roundTrip(ctx, cn, args)
+	}
+	if err != nil {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+
+	if e, ok := v.(Error); ok {
+		return nil, e
+	}
+
+	return v, nil
+}
+
+// Close closes the idle connections.
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.pool:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, cn *conn, args []string) (any, error) {
+	deadline := time.Now().Add(c.ioTimeout)
+	ctxDeadline := false
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+		ctxDeadline = true
+	}
+
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := writeCommand(cn.w, args); err != nil {
+		return nil, fmt.Errorf("resp: write: %w", deadlineError(err, ctxDeadline))
+	}
+
+	v, err := readValue(cn.r)
+	if err != nil {
+		return nil, fmt.Errorf("resp: read: %w", deadlineError(err, ctxDeadline))
+	}
+
+	return v, nil
+}
+
+// deadlineError reports a timeout of the connection as the deadline of the
+// context expiring when the connection used it, since the connection can
+// time out before the context notices.
+func deadlineError(err error, ctxDeadline bool) error {
+	var netErr net.Error
+	if ctxDeadline && errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+
+	return err
+}
+
+// get returns an idle connection or a new one, reporting which.
+func (c *Client) get(ctx context.Context) (*conn, bool, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, true, nil
+	default:
+	}
+
+	cn, err := c.dial(ctx)
+	return cn, false, err
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.dialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("resp: dial: %w", err)
+	}
+
+	cn := conn{
+		Conn: nc,
+		r:    bufio.NewReader(nc),
+		w:    bufio.NewWriter(nc),
+	}
+
+	if c.password != "" {
+		v, err := c.roundTrip(ctx, &cn, []string{"AUTH", c.password})
+		if err == nil {
+			if e, ok := v.(Error); ok {
+				err = e
+			}
+		}
+		if err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("resp: auth: %w", err)
+		}
+	}
+
+	return &cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.pool <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// isStale reports whether the error means the server closed the connection.
+func isStale(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// =============================================================================
+
+// Int converts a reply to an integer.
+func Int(v any) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return 0, fmt.Errorf("resp: unexpected reply type %T", v)
+}
+
+// IsServerError reports whether the error is an error reply of the server,
+// which means the server is reachable.
+func IsServerError(err error) bool {
+	var e Error
+	return errors.As(err, &e)
+}
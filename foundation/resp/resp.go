@@ -0,0 +1,146 @@
+// Package resp implements the Redis serialization protocol (RESP2) with a
+// small client and a stand-in server, so shared state can be kept in Redis
+// and tested without one.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is an error reply sent by the server, as opposed to a failure to
+// talk to the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// SimpleString is a status reply like OK.
+type SimpleString string
+
+// maxBulkLen bounds the size of a bulk string read from the network.
+const maxBulkLen = 512 << 20
+
+// maxArrayLen bounds the number of elements of an array read from the
+// network, like the multibulk limit of Redis.
+const maxArrayLen = 1 << 20
+
+// errProtocol is returned when the peer doesn't speak RESP.
+var errProtocol = errors.New("resp: protocol error")
+
+// writeCommand writes the arguments as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args []string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	return w.Flush()
+}
+
+// writeValue writes a reply. Supported values are nil, SimpleString,
+// string, int, int64, Error, error and []any of these.
+func writeValue(w *bufio.Writer, v any) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case SimpleString:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case Error:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case error:
+		fmt.Fprintf(w, "-ERR %s\r\n", v)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeValue(w, item)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR unsupported reply type %T\r\n", v)
+	}
+}
+
+// readValue reads a reply. Bulk strings are returned as string, integers as
+// int64, arrays as []any and error replies as Error.
+func readValue(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return SimpleString(line[1:]), nil
+
+	case '-':
+		return Error(line[1:]), nil
+
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, errProtocol
+		}
+		return n, nil
+
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxBulkLen {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArrayLen {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	return nil, errProtocol
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errProtocol
+	}
+
+	return line[:len(line)-2], nil
+}
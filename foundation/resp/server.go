@@ -0,0 +1,443 @@
+package resp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScriptFunc is the Go equivalent of a Lua script, run by the stand-in
+// server atomically like Redis runs scripts.
+type ScriptFunc func(db *DB, keys []string, args []string) (any, error)
+
+// Server is a stand-in for Redis speaking enough of the protocol to run the
+// commands of this module: PING, ECHO, AUTH, GET, SET, DEL, PTTL, TIME,
+// FLUSHALL, SCRIPT LOAD/EXISTS, EVAL and EVALSHA. It can't run Lua, so
+// scripts must be registered with their Go equivalent.
+type Server struct {
+	mu      sync.Mutex
+	db      DB
+	scripts map[string]ScriptFunc
+	loaded  map[string]bool
+
+	connMu sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer constructs an empty stand-in server.
+func NewServer() *Server {
+	return &Server{
+		db: DB{
+			data: make(map[string]item),
+			now:  time.Now,
+		},
+		scripts: make(map[string]ScriptFunc),
+		loaded:  make(map[string]bool),
+	}
+}
+
+// RegisterScript registers the Go equivalent of the Lua source. The script
+// can then be run with EVAL or, once loaded, with EVALSHA.
+func (s *Server) RegisterScript(src string, fn ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripts[ScriptSHA(src)] = fn
+}
+
+// ListenAndServe listens on the TCP address and serves connections until
+// the server is closed.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ln)
+}
+
+// Serve serves the connections of the listener until the server is closed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		ln.Close()
+		return net.ErrClosed
+	}
+	s.ln = ln
+	s.conns = make(map[net.Conn]struct{})
+	s.connMu.Unlock()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.connMu.Lock()
+		s.conns[nc] = struct{}{}
+		s.connMu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(nc)
+	}
+}
+
+// Close stops the listener and closes every connection.
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	s.closed = true
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for nc := range s.conns {
+		nc.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	defer func() {
+		nc.Close()
+
+		s.connMu.Lock()
+		delete(s.conns, nc)
+		s.connMu.Unlock()
+
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				writeValue(w, Error("ERR "+err.Error()))
+				w.Flush()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		if strings.EqualFold(args[0], "QUIT") {
+			writeValue(w, SimpleString("OK"))
+			w.Flush()
+			return
+		}
+
+		writeValue(w, s.exec(args))
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads an array of bulk strings or an inline command.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if b[0] != '*' {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(line), nil
+	}
+
+	v, err := readValue(r)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := v.([]any)
+	args := make([]string, len(items))
+	for i, item := range items {
+		arg, ok := item.(string)
+		if !ok {
+			return nil, errProtocol
+		}
+		args[i] = arg
+	}
+
+	return args, nil
+}
+
+func (s *Server) exec(args []string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd, args := strings.ToUpper(args[0]), args[1:]
+
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			return args[0]
+		}
+		return SimpleString("PONG")
+
+	case "ECHO":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		return args[0]
+
+	case "AUTH", "SELECT":
+		return SimpleString("OK")
+
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		if v, ok := s.db.Get(args[0]); ok {
+			return v
+		}
+		return nil
+
+	case "SET":
+		return s.set(args)
+
+	case "DEL":
+		var n int64
+		for _, key := range args {
+			if _, ok := s.db.Get(key); ok {
+				s.db.Del(key)
+				n++
+			}
+		}
+		return n
+
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		return s.db.pttl(args[0])
+
+	case "TIME":
+		now := s.db.Now()
+		return []any{strconv.FormatInt(now.Unix(), 10), strconv.Itoa(now.Nanosecond() / 1000)}
+
+	case "FLUSHALL", "FLUSHDB":
+		s.db.data = make(map[string]item)
+		return SimpleString("OK")
+
+	case "SCRIPT":
+		return s.script(args)
+
+	case "EVAL":
+		if len(args) < 2 {
+			return wrongArgs(cmd)
+		}
+		sha := ScriptSHA(args[0])
+		if _, ok := s.scripts[sha]; !ok {
+			return Error("ERR the stand-in server can't run Lua, register the script")
+		}
+		s.loaded[sha] = true
+		return s.eval(sha, args[1:])
+
+	case "EVALSHA":
+		if len(args) < 2 {
+			return wrongArgs(cmd)
+		}
+		sha := strings.ToLower(args[0])
+		if !s.loaded[sha] {
+			return Error("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return s.eval(sha, args[1:])
+	}
+
+	return Error(fmt.Sprintf("ERR unknown command '%s'", cmd))
+}
+
+func (s *Server) set(args []string) any {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+
+	key, value := args[0], args[1]
+
+	var ttl time.Duration
+	var nx bool
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "PX", "EX":
+			if i+1 >= len(args) {
+				return Error("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return Error("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Millisecond
+			if strings.EqualFold(args[i], "EX") {
+				unit = time.Second
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return Error("ERR syntax error")
+		}
+	}
+
+	if _, exists := s.db.Get(key); exists && nx {
+		return nil
+	}
+
+	s.db.Set(key, value, ttl)
+	return SimpleString("OK")
+}
+
+func (s *Server) script(args []string) any {
+	if len(args) == 0 {
+		return wrongArgs("SCRIPT")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "LOAD":
+		if len(args) != 2 {
+			return wrongArgs("SCRIPT LOAD")
+		}
+		sha := ScriptSHA(args[1])
+		if _, ok := s.scripts[sha]; !ok {
+			return Error("ERR the stand-in server can't run Lua, register the script")
+		}
+		s.loaded[sha] = true
+		return sha
+
+	case "EXISTS":
+		exists := make([]any, len(args)-1)
+		for i, sha := range args[1:] {
+			exists[i] = int64(0)
+			if s.loaded[strings.ToLower(sha)] {
+				exists[i] = int64(1)
+			}
+		}
+		return exists
+
+	case "FLUSH":
+		s.loaded = make(map[string]bool)
+		return SimpleString("OK")
+	}
+
+	return Error("ERR unknown SCRIPT subcommand")
+}
+
+func (s *Server) eval(sha string, args []string) any {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys < 0 || numKeys > len(args)-1 {
+		return Error("ERR Number of keys can't be greater than number of args")
+	}
+
+	keys := args[1 : 1+numKeys]
+	argv := args[1+numKeys:]
+
+	v, err := s.scripts[sha](&s.db, keys, argv)
+	if err != nil {
+		return Error("ERR " + err.Error())
+	}
+
+	return v
+}
+
+func wrongArgs(cmd string) Error {
+	return Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+// ScriptSHA returns the SHA1 digest Redis uses to identify a script.
+func ScriptSHA(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
+
+// =============================================================================
+
+type item struct {
+	value   string
+	expires time.Time
+}
+
+// DB is the key space of the stand-in server given to scripts.
+type DB struct {
+	data map[string]item
+	now  func() time.Time
+}
+
+// Now returns the time of the server, as the TIME command does.
+func (db *DB) Now() time.Time {
+	return db.now()
+}
+
+// Get returns the value of the key unless it doesn't exist or expired.
+func (db *DB) Get(key string) (string, bool) {
+	it, exists := db.data[key]
+	if !exists {
+		return "", false
+	}
+
+	if !it.expires.IsZero() && !db.now().Before(it.expires) {
+		delete(db.data, key)
+		return "", false
+	}
+
+	return it.value, true
+}
+
+// Set sets the value of the key, which expires after the ttl if positive.
+func (db *DB) Set(key, value string, ttl time.Duration) {
+	it := item{value: value}
+	if ttl > 0 {
+		it.expires = db.now().Add(ttl)
+	}
+
+	db.data[key] = it
+}
+
+// Del removes the key.
+func (db *DB) Del(key string) {
+	delete(db.data, key)
+}
+
+func (db *DB) pttl(key string) int64 {
+	if _, exists := db.Get(key); !exists {
+		return -2
+	}
+
+	it := db.data[key]
+	if it.expires.IsZero() {
+		return -1
+	}
+
+	return it.expires.Sub(db.now()).Milliseconds()
+}
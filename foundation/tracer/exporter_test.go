@@ -0,0 +1,94 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInMemoryExporter(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tr := New("test", WithExporter(exporter))
+
+	ctx, parent := tr.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tr.Start(ctx, "child", SpanKindInternal)
+
+	child.SetAttributes("key", "value", 1, "ignored")
+	child.SetError(errors.New("failed"))
+	child.End()
+	child.End()
+	parent.End()
+
+	// Attributes set after the end don't change the exported span.
+	child.SetAttributes("late", true)
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	tests := []struct {
+		name   string
+		got    SpanData
+		kind   string
+		parent string
+		attrs  int
+		status StatusCode
+	}{
+		{name: "child", got: spans[0], kind: "internal", parent: parent.SpanContext().SpanID.String(), attrs: 1, status: StatusError},
+		{name: "parent", got: spans[1], kind: "server", status: StatusUnset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.Name != tt.name {
+				t.Errorf("got name %s, want %s", tt.got.Name, tt.name)
+			}
+			if tt.got.Kind != tt.kind {
+				t.Errorf("got kind %s, want %s", tt.got.Kind, tt.kind)
+			}
+			if tt.got.ParentSpanID != tt.parent {
+				t.Errorf("got parent %q, want %q", tt.got.ParentSpanID, tt.parent)
+			}
+			if len(tt.got.Attributes) != tt.attrs {
+				t.Errorf("got attributes %v, want %d", tt.got.Attributes, tt.attrs)
+			}
+			if tt.got.Status != tt.status {
+				t.Errorf("got status %d, want %d", tt.got.Status, tt.status)
+			}
+			if tt.got.TraceID != parent.SpanContext().TraceID.String() {
+				t.Errorf("got trace ID %s, want %s", tt.got.TraceID, parent.SpanContext().TraceID)
+			}
+		})
+	}
+
+	exporter.Reset()
+	if got := len(exporter.Spans()); got != 0 {
+		t.Errorf("got %d spans after reset, want 0", got)
+	}
+}
+
+func TestInMemoryExporterSampling(t *testing.T) {
+	tests := []struct {
+		name  string
+		ratio float64
+		spans int
+	}{
+		{name: "all", ratio: 1, spans: 1},
+		{name: "none", ratio: 0, spans: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := NewInMemoryExporter()
+			tr := New("test", WithExporter(exporter), WithSampleRatio(tt.ratio))
+
+			_, span := tr.Start(context.Background(), "span", SpanKindInternal)
+			span.End()
+
+			if got := len(exporter.Spans()); got != tt.spans {
+				t.Errorf("got %d spans, want %d", got, tt.spans)
+			}
+		})
+	}
+}
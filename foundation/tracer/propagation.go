@@ -0,0 +1,105 @@
+package tracer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Headers defined by the W3C Trace Context specification.
+const (
+	TraceParentHeader = "Traceparent"
+	TraceStateHeader  = "Tracestate"
+)
+
+// maxTraceStateLen is the size the specification requires to propagate.
+const maxTraceStateLen = 512
+
+// Extract returns a copy of the context containing the span context found
+// in the traceparent and tracestate headers. Invalid headers are ignored so
+// the next span starts a new trace.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := parseTraceParent(header.Get(TraceParentHeader))
+	if !ok {
+		return ctx
+	}
+
+	if state := strings.Join(header.Values(TraceStateHeader), ","); len(state) <= maxTraceStateLen {
+		sc.TraceState = strings.TrimSpace(state)
+	}
+
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject sets the traceparent and tracestate headers for the span in the
+// context so the called service continues the trace.
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := parentSpanContext(ctx)
+	if !ok {
+		return
+	}
+
+	var flags byte
+	if sc.Sampled {
+		flags = 1
+	}
+
+	header.Set(TraceParentHeader, fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags))
+	if sc.TraceState != "" {
+		header.Set(TraceStateHeader, sc.TraceState)
+	}
+}
+
+// parseTraceParent parses a header like
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceParent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, false
+	}
+
+	var version [1]byte
+	if !decodeLowerHex(version[:], value[0:2]) || version[0] == 0xff {
+		return SpanContext{}, false
+	}
+
+	// Version 00 has an exact size while future versions can append fields.
+	if version[0] == 0 && len(value) != 55 {
+		return SpanContext{}, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, false
+	}
+
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], value[3:35]) || !decodeLowerHex(sc.SpanID[:], value[36:52]) {
+		return SpanContext{}, false
+	}
+
+	var flags [1]byte
+	if !decodeLowerHex(flags[:], value[53:55]) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+func decodeLowerHex(dst []byte, s string) bool {
+	if s != strings.ToLower(s) {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
@@ -0,0 +1,106 @@
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		trace   string
+		span    string
+		sampled bool
+	}{
+		{
+			name:    "sampled",
+			value:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			ok:      true,
+			trace:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			span:    "00f067aa0ba902b7",
+			sampled: true,
+		},
+		{
+			name:  "not sampled",
+			value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			ok:    true,
+			trace: "4bf92f3577b34da6a3ce929d0e0e4736",
+			span:  "00f067aa0ba902b7",
+		},
+		{
+			name:    "future version with fields",
+			value:   "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			ok:      true,
+			trace:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			span:    "00f067aa0ba902b7",
+			sampled: true,
+		},
+		{name: "empty", value: ""},
+		{name: "short", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0"},
+		{name: "version 00 with fields", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "version ff", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "uppercase", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "zero trace", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "bad separator", value: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "not hex", value: "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceParent(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("got ok %t, want %t", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+
+			if got := sc.TraceID.String(); got != tt.trace {
+				t.Errorf("got trace ID %s, want %s", got, tt.trace)
+			}
+			if got := sc.SpanID.String(); got != tt.span {
+				t.Errorf("got span ID %s, want %s", got, tt.span)
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("got sampled %t, want %t", sc.Sampled, tt.sampled)
+			}
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	in := http.Header{}
+	in.Set(TraceParentHeader, traceParent)
+	in.Set(TraceStateHeader, "vendor=value")
+
+	ctx := Extract(context.Background(), in)
+
+	out := http.Header{}
+	Inject(ctx, out)
+
+	if got := out.Get(TraceParentHeader); got != traceParent {
+		t.Errorf("got traceparent %s, want %s", got, traceParent)
+	}
+	if got := out.Get(TraceStateHeader); got != "vendor=value" {
+		t.Errorf("got tracestate %s, want vendor=value", got)
+	}
+
+	ctx, span := New("test").Start(ctx, "child", SpanKindClient)
+
+	out = http.Header{}
+	Inject(ctx, out)
+
+	sc, ok := parseTraceParent(out.Get(TraceParentHeader))
+	if !ok {
+		t.Fatalf("got invalid traceparent %q", out.Get(TraceParentHeader))
+	}
+	if sc.TraceID != span.SpanContext().TraceID || sc.SpanID != span.SpanContext().SpanID {
+		t.Errorf("got %s-%s, want the IDs of the child span", sc.TraceID, sc.SpanID)
+	}
+}
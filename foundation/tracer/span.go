@@ -0,0 +1,259 @@
+package tracer
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace across every service it crosses.
+type TraceID [16]byte
+
+// IsValid reports whether the ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether the span context has a trace and span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship of a span with its callers and
+// callees.
+type SpanKind int
+
+// Set of span kinds.
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+var kindNames = map[SpanKind]string{
+	SpanKindInternal: "internal",
+	SpanKindServer:   "server",
+	SpanKindClient:   "client",
+}
+
+func (k SpanKind) String() string {
+	return kindNames[k]
+}
+
+// StatusCode is the outcome of the operation of a span.
+type StatusCode int
+
+// Set of status codes.
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Attr is a key/value pair describing a span.
+type Attr struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// SpanData is the read only copy of an ended span given to the exporter.
+type SpanData struct {
+	Service       string        `json:"service"`
+	Name          string        `json:"name"`
+	Kind          string        `json:"kind"`
+	TraceID       string        `json:"trace_id"`
+	SpanID        string        `json:"span_id"`
+	ParentSpanID  string        `json:"parent_span_id,omitempty"`
+	TraceState    string        `json:"trace_state,omitempty"`
+	Sampled       bool          `json:"sampled"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	Duration      time.Duration `json:"duration"`
+	Attributes    []Attr        `json:"attributes,omitempty"`
+	Status        StatusCode    `json:"status"`
+	StatusMessage string        `json:"status_message,omitempty"`
+}
+
+// Span represents an operation within a trace. A nil span is valid and
+// ignores every call, so callers don't need to check if tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanID
+	start  time.Time
+
+	mu      sync.Mutex
+	attrs   []Attr
+	status  StatusCode
+	message string
+	ended   bool
+}
+
+// SpanContext returns the identity of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes adds the key/value pairs to the span. Arguments follow the
+// same rules as the logger: a key followed by its value.
+func (s *Span) SetAttributes(args ...any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+		s.attrs = append(s.attrs, Attr{Key: key, Value: args[i+1]})
+	}
+}
+
+// SetStatus sets the outcome of the operation.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = code
+	s.message = message
+}
+
+// SetError marks the operation as failed with the error.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End completes the span and sends it to the exporter. Calls after the
+// first one are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	end := time.Now()
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+
+	data := SpanData{
+		Service:       s.tracer.service,
+		Name:          s.name,
+		Kind:          s.kind.String(),
+		TraceID:       s.sc.TraceID.String(),
+		SpanID:        s.sc.SpanID.String(),
+		TraceState:    s.sc.TraceState,
+		Sampled:       s.sc.Sampled,
+		Start:         s.start,
+		End:           end,
+		Duration:      end.Sub(s.start),
+		Attributes:    slices.Clone(s.attrs),
+		Status:        s.status,
+		StatusMessage: s.message,
+	}
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	s.mu.Unlock()
+
+	s.tracer.export(data)
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const (
+	spanKey ctxKey = iota + 1
+	remoteKey
+)
+
+// ContextWithSpan returns a copy of the context containing the span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext returns the current span of the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of the context containing the
+// span context of a caller, used as parent by the next span started.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// parentSpanContext returns the span context the next span should be a
+// child of, preferring a local span to a remote one.
+func parentSpanContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc, true
+	}
+
+	if sc, ok := ctx.Value(remoteKey).(SpanContext); ok && sc.IsValid() {
+		return sc, true
+	}
+
+	return SpanContext{}, false
+}
+
+// LogIDs can be provided to logger.New so every record logged with a traced
+// context contains the trace and span IDs.
+func LogIDs(ctx context.Context) []any {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	return []any{"trace_id", span.sc.TraceID.String(), "span_id", span.sc.SpanID.String()}
+}
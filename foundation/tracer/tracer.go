@@ -0,0 +1,156 @@
+// Package tracer provides distributed tracing compatible with the W3C Trace
+// Context specification so spans can be correlated across services.
+package tracer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Options represent optional parameters.
+type Options struct {
+	exporter    Exporter
+	sampleRatio float64
+	onError     func(err error)
+}
+
+// WithExporter sets the exporter that receives the sampled spans when they
+// end. Without an exporter spans are still propagated but not recorded.
+func WithExporter(exporter Exporter) func(opts *Options) {
+	return func(opts *Options) {
+		opts.exporter = exporter
+	}
+}
+
+// WithSampleRatio sets the ratio of new traces that are sampled, between 0
+// and 1. Traces started by a caller keep the sampling decision of the
+// caller.
+func WithSampleRatio(ratio float64) func(opts *Options) {
+	return func(opts *Options) {
+		opts.sampleRatio = ratio
+	}
+}
+
+// WithErrorHandler sets the function called when the exporter fails.
+func WithErrorHandler(fn func(err error)) func(opts *Options) {
+	return func(opts *Options) {
+		opts.onError = fn
+	}
+}
+
+// Tracer starts spans for a service.
+type Tracer struct {
+	service     string
+	exporter    Exporter
+	sampleBound uint64
+	onError     func(err error)
+}
+
+// New constructs a tracer for the named service. Every new trace is sampled
+// unless a sample ratio is provided.
+func New(service string, options ...func(opts *Options)) *Tracer {
+	opts := Options{
+		sampleRatio: 1,
+		onError:     func(error) {},
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Tracer{
+		service:     service,
+		exporter:    opts.exporter,
+		sampleBound: sampleBound(opts.sampleRatio),
+		onError:     opts.onError,
+	}
+}
+
+// Start starts a span as a child of the span in the context, or of the
+// remote span extracted from the headers of the request. A span without
+// parent starts a new trace. The returned context contains the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+
+	parent, ok := parentSpanContext(ctx)
+	switch {
+	case ok:
+		span.sc = SpanContext{
+			TraceID:    parent.TraceID,
+			Sampled:    parent.Sampled,
+			TraceState: parent.TraceState,
+		}
+		span.parent = parent.SpanID
+
+	default:
+		span.sc.TraceID = newTraceID()
+		span.sc.Sampled = t.sample(span.sc.TraceID)
+	}
+	span.sc.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, &span), &span
+}
+
+// Start starts a child of the span in the context with the tracer of that
+// span. It returns a nil span, which is safe to use, when the context
+// doesn't contain a span so libraries can trace calls made outside a
+// traced request without a tracer of their own.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.Start(ctx, name, kind)
+}
+
+// export sends the ended span to the exporter.
+func (t *Tracer) export(data SpanData) {
+	if t.exporter == nil || !data.Sampled {
+		return
+	}
+
+	if err := t.exporter.Export(context.Background(), []SpanData{data}); err != nil {
+		t.onError(err)
+	}
+}
+
+// sample decides if a new trace is sampled from the random part of its ID
+// so every service makes the same decision for a trace.
+func (t *Tracer) sample(id TraceID) bool {
+	return binary.BigEndian.Uint64(id[8:])>>1 < t.sampleBound
+}
+
+func sampleBound(ratio float64) uint64 {
+	switch {
+	case ratio >= 1:
+		return math.MaxUint64
+	case ratio <= 0:
+		return 0
+	}
+
+	return uint64(ratio * (1 << 63))
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,338 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// check validates a value with a rule bound to its parameter.
+type check func(v reflect.Value) error
+
+// compiler checks the parameter of a built-in rule and the type of the field
+// it's applied to when a tag is parsed, and returns the bound check. The
+// checks can then assume the kind of the value.
+type compiler func(t reflect.Type, param string) (check, error)
+
+var builtins = map[string]compiler{
+	"required": compileRequired,
+	"min":      compileMin,
+	"max":      compileMax,
+	"len":      compileLen,
+	"email":    compileEmail,
+	"uuid":     compileUUID,
+	"oneof":    compileOneOf,
+	"regex":    compileRegex,
+}
+
+// compile returns the check of the named rule for a field of type t. A
+// registered rule takes precedence over the built-in rule of the same name.
+func compile(name string, param string, t reflect.Type) (check, error) {
+	if rule, exists := lookup(name); exists {
+		return func(v reflect.Value) error { return rule(v, param) }, nil
+	}
+
+	c, exists := builtins[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return c(t, param)
+}
+
+func compileRequired(_ reflect.Type, param string) (check, error) {
+	if param != "" {
+		return nil, fmt.Errorf("required takes no parameter")
+	}
+
+	return func(v reflect.Value) error {
+		if v.IsZero() {
+			return fmt.Errorf("is required")
+		}
+
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+			return fmt.Errorf("is required")
+		}
+
+		return nil
+	}, nil
+}
+
+func compileMin(t reflect.Type, param string) (check, error) {
+	return compileLimit("min", t, param,
+		func(n, limit float64) bool { return n >= limit },
+		"must contain at least %d %s",
+		"must be greater than or equal to %s",
+	)
+}
+
+func compileMax(t reflect.Type, param string) (check, error) {
+	return compileLimit("max", t, param,
+		func(n, limit float64) bool { return n <= limit },
+		"must contain at most %d %s",
+		"must be less than or equal to %s",
+	)
+}
+
+// compileLimit compiles the min and max rules, which bound the size of
+// strings and containers and the value of numbers.
+func compileLimit(name string, t reflect.Type, param string, valid func(n, limit float64) bool, sizeMsg string, numberMsg string) (check, error) {
+	t = indirectType(t)
+
+	switch {
+	case sized(t):
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid %s parameter %q", name, param)
+		}
+		msg := fmt.Sprintf(sizeMsg, limit, unit(t))
+
+		return func(v reflect.Value) error {
+			v, ok := indirect(v)
+			if !ok {
+				return nil
+			}
+			if !valid(float64(size(v)), float64(limit)) {
+				return errors.New(msg)
+			}
+			return nil
+		}, nil
+
+	case numeric(t):
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter %q", name, param)
+		}
+		msg := fmt.Sprintf(numberMsg, param)
+
+		return func(v reflect.Value) error {
+			v, ok := indirect(v)
+			if !ok {
+				return nil
+			}
+			if !valid(number(v), limit) {
+				return errors.New(msg)
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%s doesn't support the type %s", name, t)
+}
+
+func compileLen(t reflect.Type, param string) (check, error) {
+	t = indirectType(t)
+	if !sized(t) {
+		return nil, fmt.Errorf("len doesn't support the type %s", t)
+	}
+
+	limit, err := strconv.Atoi(param)
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("invalid len parameter %q", param)
+	}
+	msg := fmt.Sprintf("must contain exactly %d %s", limit, unit(t))
+
+	return func(v reflect.Value) error {
+		v, ok := indirect(v)
+		if !ok {
+			return nil
+		}
+		if size(v) != limit {
+			return errors.New(msg)
+		}
+		return nil
+	}, nil
+}
+
+func compileEmail(t reflect.Type, param string) (check, error) {
+	if err := stringRule("email", t, param); err != nil {
+		return nil, err
+	}
+
+	return func(v reflect.Value) error {
+		v, ok := indirect(v)
+		if !ok {
+			return nil
+		}
+
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return fmt.Errorf("must be a valid email address")
+		}
+		return nil
+	}, nil
+}
+
+func compileUUID(t reflect.Type, param string) (check, error) {
+	if err := stringRule("uuid", t, param); err != nil {
+		return nil, err
+	}
+
+	return func(v reflect.Value) error {
+		v, ok := indirect(v)
+		if !ok {
+			return nil
+		}
+
+		if !uuidRegex.MatchString(v.String()) {
+			return fmt.Errorf("must be a valid UUID")
+		}
+		return nil
+	}, nil
+}
+
+func compileOneOf(t reflect.Type, param string) (check, error) {
+	t = indirectType(t)
+
+	var format func(v reflect.Value) string
+	switch t.Kind() {
+	case reflect.String:
+		format = reflect.Value.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		format = func(v reflect.Value) string { return strconv.FormatInt(v.Int(), 10) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		format = func(v reflect.Value) string { return strconv.FormatUint(v.Uint(), 10) }
+	default:
+		return nil, fmt.Errorf("oneof doesn't support the type %s", t)
+	}
+
+	options := strings.Fields(param)
+	if len(options) == 0 {
+		return nil, fmt.Errorf("oneof requires at least one option")
+	}
+	msg := fmt.Sprintf("must be one of [%s]", strings.Join(options, ", "))
+
+	return func(v reflect.Value) error {
+		v, ok := indirect(v)
+		if !ok {
+			return nil
+		}
+
+		if !slices.Contains(options, format(v)) {
+			return errors.New(msg)
+		}
+		return nil
+	}, nil
+}
+
+func compileRegex(t reflect.Type, param string) (check, error) {
+	if t = indirectType(t); t.Kind() != reflect.String {
+		return nil, fmt.Errorf("regex doesn't support the type %s", t)
+	}
+
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex parameter %q: %w", param, err)
+	}
+	msg := fmt.Sprintf("must match the pattern %s", param)
+
+	return func(v reflect.Value) error {
+		v, ok := indirect(v)
+		if !ok {
+			return nil
+		}
+
+		if !re.MatchString(v.String()) {
+			return errors.New(msg)
+		}
+		return nil
+	}, nil
+}
+
+// stringRule checks a rule that takes no parameter and only applies to
+// strings.
+func stringRule(name string, t reflect.Type, param string) error {
+	if t = indirectType(t); t.Kind() != reflect.String {
+		return fmt.Errorf("%s doesn't support the type %s", name, t)
+	}
+	if param != "" {
+		return fmt.Errorf("%s takes no parameter", name)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// indirect dereferences the pointers of the value. It reports false for a
+// nil pointer, which every rule except required accepts since the value is
+// absent.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+
+	return v, true
+}
+
+// indirectType returns the type the pointer type points to.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// sized reports whether the min, max and len rules apply to the length of
+// values of the type.
+func sized(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+
+	return false
+}
+
+func numeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// size returns the length used by the min, max and len rules for strings
+// and containers.
+func size(v reflect.Value) int {
+	if v.Kind() == reflect.String {
+		return utf8.RuneCountInString(v.String())
+	}
+
+	return v.Len()
+}
+
+func unit(t reflect.Type) string {
+	if t.Kind() == reflect.String {
+		return "characters"
+	}
+
+	return "items"
+}
+
+// number returns the numeric value as float64.
+func number(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+
+	return v.Float()
+}
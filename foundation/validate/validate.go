@@ -0,0 +1,359 @@
+// Package validate provides struct tag driven validation that reports
+// failures as FieldErrors.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldError is a failed rule of a single field.
+type FieldError struct {
+	Field string `json:"field"`
+	Err   string `json:"error"`
+}
+
+// FieldErrors is the collection of fields that failed validation.
+type FieldErrors []FieldError
+
+// Add adds a field error to the collection.
+func (fe *FieldErrors) Add(field string, err error) {
+	*fe = append(*fe, FieldError{
+		Field: field,
+		Err:   err.Error(),
+	})
+}
+
+// Error implements the error interface.
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, f := range fe {
+		msgs[i] = f.Field + ": " + f.Err
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// =============================================================================
+
+// Rule validates a single value. The param is the text after the "=" in the
+// tag, for example "3" for "min=3".
+type Rule func(v reflect.Value, param string) error
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{}
+)
+
+// Register adds a custom rule that can be referenced by name in the validate
+// struct tag. Registering an existing name, including the name of a built-in
+// rule, replaces the rule. Rules must be registered before a struct using
+// them is first checked. Custom rules receive values of any type the tag is
+// applied to and their parameters aren't checked when the tag is parsed.
+func Register(name string, rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rules[name] = rule
+}
+
+func lookup(name string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rule, exists := rules[name]
+	return rule, exists
+}
+
+// Check validates the fields of the struct v based on the validate struct
+// tags. Rules are separated by commas and run in order:
+//
+//	type User struct {
+//		Name  string   `json:"name" validate:"required,min=2,max=50"`
+//		Email string   `json:"email" validate:"required,email"`
+//		Role  string   `json:"role" validate:"oneof=admin user"`
+//		Tags  []string `json:"tags" validate:"max=5,dive,min=1"`
+//		Code  string   `json:"code" validate:"omitempty,regex=^[A-Z]{3}$"`
+//	}
+//
+// The omitempty rule skips the remaining rules for zero values, dive applies
+// the rules that follow it to every element of a slice, array or map, and
+// regex consumes the rest of the tag so the pattern can contain commas.
+// Nested structs are validated recursively. Rules other than required pass
+// for nil pointers, so optional fields are only checked when present.
+// Failures are returned as FieldErrors with the JSON field names.
+//
+// The tags of a struct type are parsed the first time it's checked and Check
+// panics when a tag names an unknown rule, has an invalid parameter or
+// applies a rule to a type it doesn't support, since that's a programming
+// error and not something the client can fix.
+func Check(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fieldErrs FieldErrors
+	checkStruct(rv, "", &fieldErrs)
+
+	if len(fieldErrs) > 0 {
+		return fieldErrs
+	}
+
+	return nil
+}
+
+func checkStruct(rv reflect.Value, prefix string, fieldErrs *FieldErrors) {
+	for _, f := range fieldsOf(rv.Type()) {
+		path := f.name
+		if prefix != "" && f.name != "" {
+			path = prefix + "." + f.name
+		} else if prefix != "" {
+			path = prefix
+		}
+
+		checkValue(rv.Field(f.index), path, f.rules, fieldErrs)
+	}
+}
+
+// checkValue runs the tag rules against the value and descends into
+// nested structs and, after a dive, into the elements.
+func checkValue(v reflect.Value, path string, tag []tagRule, fieldErrs *FieldErrors) {
+	for i, r := range tag {
+		switch r.name {
+		case "omitempty":
+			if v.IsZero() {
+				return
+			}
+			continue
+
+		case "dive":
+			checkElements(v, path, tag[i+1:], fieldErrs)
+			return
+		}
+
+		if err := r.check(v); err != nil {
+			fieldErrs.Add(path, err)
+			return
+		}
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && v.Type() != reflect.TypeFor[time.Time]():
+		checkStruct(v, path, fieldErrs)
+
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map:
+		checkElements(v, path, nil, fieldErrs)
+	}
+}
+
+// checkElements validates every element of a container with the rules. When
+// no rules are provided only struct elements are checked.
+func checkElements(v reflect.Value, path string, tag []tagRule, fieldErrs *FieldErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if len(tag) == 0 && !hasStructs(v.Type().Elem()) {
+			return
+		}
+		for i := range v.Len() {
+			checkValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), tag, fieldErrs)
+		}
+
+	case reflect.Map:
+		if len(tag) == 0 && !hasStructs(v.Type().Elem()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			checkValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), tag, fieldErrs)
+		}
+	}
+}
+
+func hasStructs(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != reflect.TypeFor[time.Time]()
+}
+
+// =============================================================================
+
+// field is a parsed exported field of a struct.
+type field struct {
+	index int
+	name  string
+	rules []tagRule
+}
+
+// tagRule is a parsed rule of a tag. The check is nil for omitempty and
+// dive.
+type tagRule struct {
+	name  string
+	check check
+}
+
+// fields holds the parsed fields of every struct type checked so far.
+var fields sync.Map
+
+// fieldsOf returns the parsed fields of the struct type. The type and the
+// struct types it contains are parsed the first time the type is seen.
+func fieldsOf(t reflect.Type) []field {
+	if fs, ok := fields.Load(t); ok {
+		return fs.([]field)
+	}
+
+	parse(t, make(map[reflect.Type]bool))
+
+	fs, _ := fields.Load(t)
+	return fs.([]field)
+}
+
+// parse parses the validate tags of the struct type and of the struct types
+// reachable from its fields. It panics when a tag is invalid.
+func parse(t reflect.Type, seen map[reflect.Type]bool) {
+	t = structType(t)
+	if t == nil || seen[t] {
+		return
+	}
+	if _, ok := fields.Load(t); ok {
+		return
+	}
+	seen[t] = true
+
+	var fs []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			name = ""
+		}
+
+		rules, err := parseTag(sf.Type, sf.Tag.Get("validate"))
+		if err != nil {
+			panic(fmt.Sprintf("validate: %s in the tag of %s.%s", err, t, sf.Name))
+		}
+
+		fs = append(fs, field{index: i, name: name, rules: rules})
+		parse(sf.Type, seen)
+	}
+
+	fields.Store(t, fs)
+}
+
+// parseTag compiles the rules of the tag for a field of type t. The rules
+// after a dive are compiled for the element type.
+func parseTag(t reflect.Type, tag string) ([]tagRule, error) {
+	var rules []tagRule
+	for _, r := range splitTag(tag) {
+		name, param, _ := strings.Cut(r, "=")
+
+		switch name {
+		case "omitempty":
+			rules = append(rules, tagRule{name: name})
+			continue
+
+		case "dive":
+			switch t = indirectType(t); t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			default:
+				return nil, fmt.Errorf("dive doesn't support the type %s", t)
+			}
+			rules = append(rules, tagRule{name: name})
+			continue
+		}
+
+		c, err := compile(name, param, t)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, tagRule{name: name, check: c})
+	}
+
+	return rules, nil
+}
+
+// structType returns the struct type held by t through pointers and
+// containers, or nil when there is none.
+func structType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			if t == reflect.TypeFor[time.Time]() {
+				return nil
+			}
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// splitTag separates the rules of a tag. The regex rule consumes the rest
+// of the tag.
+func splitTag(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	var parts []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			parts = append(parts, tag)
+			break
+		}
+
+		part, rest, _ := strings.Cut(tag, ",")
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+		tag = rest
+	}
+
+	return parts
+}
+
+// fieldName returns the JSON name of the field.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+
+	return name
+}
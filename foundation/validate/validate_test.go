@@ -0,0 +1,281 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	count := 3
+	negative := -1
+
+	tests := []struct {
+		name  string
+		value any
+		want  FieldErrors
+	}{
+		{
+			name: "valid",
+			value: struct {
+				Name  string   `json:"name" validate:"required,min=2,max=5"`
+				Email string   `json:"email" validate:"email"`
+				ID    string   `json:"id" validate:"uuid"`
+				Role  string   `json:"role" validate:"oneof=admin user"`
+				Level int      `json:"level" validate:"min=1,max=10"`
+				Tags  []string `json:"tags" validate:"len=2,dive,min=1"`
+			}{
+				Name:  "bob",
+				Email: "bob@example.com",
+				ID:    "0b5ac3c8-0b8e-4a35-9b0f-6c1d2b3a4f5e",
+				Role:  "user",
+				Level: 10,
+				Tags:  []string{"a", "b"},
+			},
+		},
+		{
+			name: "required",
+			value: struct {
+				Name string   `json:"name" validate:"required"`
+				Tags []string `json:"tags" validate:"required"`
+			}{Tags: []string{}},
+			want: FieldErrors{
+				{Field: "name", Err: "is required"},
+				{Field: "tags", Err: "is required"},
+			},
+		},
+		{
+			name: "sizes",
+			value: struct {
+				Name string         `json:"name" validate:"min=3"`
+				Tags []string       `json:"tags" validate:"max=1"`
+				Code string         `json:"code" validate:"len=2"`
+				Meta map[string]int `json:"meta" validate:"min=1"`
+			}{Name: "éé", Tags: []string{"a", "b"}, Code: "abc", Meta: map[string]int{}},
+			want: FieldErrors{
+				{Field: "name", Err: "must contain at least 3 characters"},
+				{Field: "tags", Err: "must contain at most 1 items"},
+				{Field: "code", Err: "must contain exactly 2 characters"},
+				{Field: "meta", Err: "must contain at least 1 items"},
+			},
+		},
+		{
+			name: "numbers",
+			value: struct {
+				Count uint    `json:"count" validate:"max=2"`
+				Score float64 `json:"score" validate:"min=0.5"`
+			}{Count: 3, Score: 0.25},
+			want: FieldErrors{
+				{Field: "count", Err: "must be less than or equal to 2"},
+				{Field: "score", Err: "must be greater than or equal to 0.5"},
+			},
+		},
+		{
+			name: "formats",
+			value: struct {
+				Email string `json:"email" validate:"email"`
+				ID    string `json:"id" validate:"uuid"`
+				Role  string `json:"role" validate:"oneof=admin user"`
+				Code  int    `json:"code" validate:"oneof=1 2"`
+			}{Email: "Bob <bob@example.com>", ID: "nope", Role: "root", Code: 3},
+			want: FieldErrors{
+				{Field: "email", Err: "must be a valid email address"},
+				{Field: "id", Err: "must be a valid UUID"},
+				{Field: "role", Err: "must be one of [admin, user]"},
+				{Field: "code", Err: "must be one of [1, 2]"},
+			},
+		},
+		{
+			name: "regex with commas",
+			value: struct {
+				Code string `json:"code" validate:"omitempty,regex=^[a-z]{2,3}$"`
+			}{Code: "abcd"},
+			want: FieldErrors{
+				{Field: "code", Err: "must match the pattern ^[a-z]{2,3}$"},
+			},
+		},
+		{
+			name: "omitempty",
+			value: struct {
+				Code string `json:"code" validate:"omitempty,len=2"`
+			}{},
+		},
+		{
+			name: "nil pointers",
+			value: struct {
+				Count *int `json:"count" validate:"min=1"`
+				Name  *string
+			}{},
+		},
+		{
+			name: "pointers",
+			value: struct {
+				Count *int `json:"count" validate:"min=1"`
+				Other *int `json:"other" validate:"min=1"`
+			}{Count: &count, Other: &negative},
+			want: FieldErrors{
+				{Field: "other", Err: "must be greater than or equal to 1"},
+			},
+		},
+		{
+			name: "dive",
+			value: struct {
+				Tags  []string          `json:"tags" validate:"dive,min=2"`
+				Codes map[string]string `json:"codes" validate:"dive,len=1"`
+			}{Tags: []string{"ok", "x"}, Codes: map[string]string{"a": "bb"}},
+			want: FieldErrors{
+				{Field: "tags[1]", Err: "must contain at least 2 characters"},
+				{Field: "codes[a]", Err: "must contain exactly 1 characters"},
+			},
+		},
+		{
+			name: "nested",
+			value: struct {
+				Home   address   `json:"home"`
+				Others []address `json:"others"`
+				Work   *address  `json:"work"`
+			}{Others: []address{{City: "x"}, {}}},
+			want: FieldErrors{
+				{Field: "home.city", Err: "is required"},
+				{Field: "others[1].city", Err: "is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.value)
+
+			var got FieldErrors
+			if err != nil && !errors.As(err, &got) {
+				t.Fatalf("got error %T, want FieldErrors", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckInvalidTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{
+			name: "unknown rule",
+			value: struct {
+				Name string `validate:"nope"`
+			}{},
+			want: `unknown rule "nope"`,
+		},
+		{
+			name: "invalid size",
+			value: struct {
+				Name string `validate:"min=abc"`
+			}{},
+			want: `invalid min parameter "abc"`,
+		},
+		{
+			name: "fractional size",
+			value: struct {
+				Tags []string `validate:"len=1.5"`
+			}{},
+			want: `invalid len parameter "1.5"`,
+		},
+		{
+			name: "invalid number",
+			value: struct {
+				Count int `validate:"max=ten"`
+			}{},
+			want: `invalid max parameter "ten"`,
+		},
+		{
+			name: "unsupported kind",
+			value: struct {
+				Count int `validate:"email"`
+			}{},
+			want: "email doesn't support the type int",
+		},
+		{
+			name: "unsupported len",
+			value: struct {
+				Count *int `validate:"len=1"`
+			}{},
+			want: "len doesn't support the type int",
+		},
+		{
+			name: "empty oneof",
+			value: struct {
+				Role string `validate:"oneof="`
+			}{},
+			want: "oneof requires at least one option",
+		},
+		{
+			name: "invalid regex",
+			value: struct {
+				Code string `validate:"regex=[a-"`
+			}{},
+			want: `invalid regex parameter "[a-"`,
+		},
+		{
+			name: "dive on a scalar",
+			value: struct {
+				Name string `validate:"dive,min=1"`
+			}{},
+			want: "dive doesn't support the type string",
+		},
+		{
+			name: "rule after dive",
+			value: struct {
+				Counts []int `validate:"dive,uuid"`
+			}{},
+			want: "uuid doesn't support the type int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("got no panic, want a panic for the invalid tag")
+				}
+				if msg := fmt.Sprint(r); !strings.Contains(msg, tt.want) {
+					t.Errorf("got panic %q, want it to contain %q", msg, tt.want)
+				}
+			}()
+
+			Check(tt.value)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(v reflect.Value, param string) error {
+		if v.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+
+	value := struct {
+		Count int `json:"count" validate:"even"`
+	}{Count: 3}
+
+	want := FieldErrors{{Field: "count", Err: "must be even"}}
+
+	var got FieldErrors
+	if err := Check(value); !errors.As(err, &got) || !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,246 @@
+package web
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/validate"
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// timeLayouts are the layouts tried in order when converting to a time.Time.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	time.DateOnly,
+	http.TimeFormat,
+}
+
+// Bind constructs a value of type T, which must be a struct, and fills its
+// fields from the request based on the struct tags:
+//
+//	type Request struct {
+//		ID     int       `path:"id"`
+//		Page   int       `query:"page"`
+//		Tags   []string  `query:"tag"`
+//		Tenant string    `header:"X-Tenant"`
+//		From   time.Time `query:"from"`
+//		Sess   *string   `cookie:"sid"`
+//	}
+//
+// Values that are not present leave the field untouched. Every conversion
+// failure is reported in an *Error with the 400 status and the failed
+// fields. The foundation layer can't import the errs package of the app, so
+// mid.Errors converts the error to an *errs.FieldErrors with the
+// errs.InvalidArgument code before it's sent to the client.
+func Bind[T any](r *http.Request) (T, error) {
+	var v T
+
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return v, fmt.Errorf("bind: %T is not a struct", v)
+	}
+
+	var fieldErrs validate.FieldErrors
+	bindStruct(r, rv, &fieldErrs)
+
+	if len(fieldErrs) > 0 {
+		return v, newFieldErrors(fieldErrs)
+	}
+
+	return v, nil
+}
+
+func bindStruct(r *http.Request, rv reflect.Value, fieldErrs *validate.FieldErrors) {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		field := rv.Field(i)
+
+		name, values, tagged := lookupValues(r, sf.Tag, isList(field.Type()))
+		if !tagged {
+			// Untagged structs are bound recursively so request models can
+			// be composed from smaller ones.
+			if field.Kind() == reflect.Struct && field.Type() != timeType {
+				bindStruct(r, field, fieldErrs)
+			}
+			continue
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(field, values); err != nil {
+			fieldErrs.Add(name, err)
+		}
+	}
+}
+
+// lookupValues returns the values of the request for the source named in the
+// struct tag. Comma separated query and header values are only split when
+// the field is a list, so scalar fields receive the raw value.
+func lookupValues(r *http.Request, tag reflect.StructTag, list bool) (string, []string, bool) {
+	if name, ok := tag.Lookup("path"); ok {
+		if v := r.PathValue(name); v != "" {
+			return name, []string{v}, true
+		}
+		return name, nil, true
+	}
+
+	if name, ok := tag.Lookup("query"); ok {
+		values := r.URL.Query()[name]
+		if list {
+			values = splitValues(values)
+		}
+		return name, values, true
+	}
+
+	if name, ok := tag.Lookup("header"); ok {
+		values := r.Header.Values(name)
+		if list {
+			values = splitValues(values)
+		}
+		return name, values, true
+	}
+
+	if name, ok := tag.Lookup("cookie"); ok {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return name, nil, true
+		}
+		return name, []string{c.Value}, true
+	}
+
+	return "", nil, false
+}
+
+// splitValues supports both repeated keys and comma separated lists.
+func splitValues(values []string) []string {
+	if len(values) != 1 || !strings.Contains(values[0], ",") {
+		return values
+	}
+
+	parts := strings.Split(values[0], ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	return parts
+}
+
+// isList reports whether the type, or the type it points to, receives every
+// value of the request.
+func isList(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// setValue converts the values into the type of the field. Slices receive
+// every value, all other types receive the first one.
+func setValue(field reflect.Value, values []string) error {
+	switch {
+	case field.Kind() == reflect.Pointer:
+		v := reflect.New(field.Type().Elem())
+		if err := setValue(v.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+
+	case isList(field.Type()):
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setScalar(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setScalar(field, values[0])
+}
+
+func setScalar(field reflect.Value, value string) error {
+	switch field.Type() {
+	case timeType:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a valid time", value)
+
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid duration", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("%q is not valid: %w", value, err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid boolean", value)
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid integer", value)
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid unsigned integer", value)
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", value)
+		}
+		field.SetFloat(n)
+
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindRequest struct {
+	ID       int            `path:"id"`
+	Query    string         `query:"q"`
+	Tags     []string       `query:"tag"`
+	Limit    *int           `query:"limit"`
+	Active   bool           `query:"active"`
+	Timeout  time.Duration  `query:"timeout"`
+	Tenant   string         `header:"X-Tenant"`
+	Accept   []string       `header:"Accept"`
+	Modified time.Time      `header:"If-Modified-Since"`
+	Session  string         `cookie:"sid"`
+	Page     bindPagination // Untagged structs are bound recursively.
+}
+
+type bindPagination struct {
+	Page int `query:"page"`
+}
+
+func TestBind(t *testing.T) {
+	limit := 10
+
+	tests := []struct {
+		name   string
+		target string
+		id     string
+		header http.Header
+		want   bindRequest
+		fields []string
+	}{
+		{
+			name:   "all sources",
+			target: "/?q=go&tag=a&tag=b&limit=10&active=true&timeout=2s&page=3",
+			id:     "7",
+			header: http.Header{
+				"X-Tenant":          {"acme"},
+				"If-Modified-Since": {"Mon, 02 Jan 2006 15:04:05 GMT"},
+				"Cookie":            {"sid=abc"},
+			},
+			want: bindRequest{
+				ID:       7,
+				Query:    "go",
+				Tags:     []string{"a", "b"},
+				Limit:    &limit,
+				Active:   true,
+				Timeout:  2 * time.Second,
+				Tenant:   "acme",
+				Modified: time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+				Session:  "abc",
+				Page:     bindPagination{Page: 3},
+			},
+		},
+		{
+			name:   "commas in scalars",
+			target: "/?q=hello,%20world",
+			header: http.Header{"X-Tenant": {"a, b"}},
+			want:   bindRequest{Query: "hello, world", Tenant: "a, b"},
+		},
+		{
+			name:   "comma lists",
+			target: "/?tag=a,%20b",
+			header: http.Header{"Accept": {"text/html, application/json"}},
+			want:   bindRequest{Tags: []string{"a", "b"}, Accept: []string{"text/html", "application/json"}},
+		},
+		{
+			name:   "missing values",
+			target: "/",
+			want:   bindRequest{},
+		},
+		{
+			name:   "conversion failures",
+			target: "/?limit=ten&active=maybe&timeout=soon",
+			id:     "x",
+			header: http.Header{"If-Modified-Since": {"yesterday"}},
+			fields: []string{"id", "limit", "active", "timeout", "If-Modified-Since"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.SetPathValue("id", tt.id)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+
+			got, err := Bind[bindRequest](r)
+
+			if tt.fields != nil {
+				var webErr *Error
+				if !errors.As(err, &webErr) {
+					t.Fatalf("got error %v, want an *Error", err)
+				}
+				if webErr.Status != http.StatusBadRequest {
+					t.Errorf("got status %d, want %d", webErr.Status, http.StatusBadRequest)
+				}
+
+				var fields []string
+				for _, f := range webErr.Fields {
+					fields = append(fields, f.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.fields) {
+					t.Errorf("got fields %v, want %v", fields, tt.fields)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("bind: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindNotStruct(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if _, err := Bind[int](r); err == nil {
+		t.Error("got nil error, want an error for a non struct type")
+	}
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/tracer"
 )
 
 var defaultClient = http.Client{
@@ -86,17 +88,22 @@ func (c *Client) QueryParam(key, v string) {
 	c.queries = append(c.queries, Query{Key: key, Value: v})
 }
 
-func (c *Client) request(method, url string, body io.Reader) (*http.Request, error) {
+func (c *Client) request(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	finalURL := url
 	if c.url != "" {
 		finalURL = c.url + url
 	}
 
-	req, err := http.NewRequest(method, finalURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, finalURL, body)
 	if err != nil {
 		return nil, err
 	}
 
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	tracer.Inject(ctx, req.Header)
+
 	if len(c.headers) > 0 {
 		for _, h := range c.headers {
 			req.Header.Set(h.Key, h.Value)
@@ -115,53 +122,60 @@ func (c *Client) request(method, url string, body io.Reader) (*http.Request, err
 }
 
 func (c *Client) Get(url string) (Response, error) {
-	req, err := c.request(http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return Response{}, err
-	}
-
-	res, err := c.http.Do(req)
-	if err != nil {
-		return Response{}, err
-	}
-
-	defer res.Body.Close()
-	buff, err := io.ReadAll(res.Body)
-	if err != nil {
-		return Response{}, err
-	}
+	return c.GetContext(context.Background(), url)
+}
 
-	response := Response{
-		StatusCode: res.StatusCode,
-		Body:       buff,
-		Entries:    res,
-	}
-	return response, nil
+// GetContext is like Get but carries the context of the request being
+// handled, forwarding its request ID and trace to the called service.
+func (c *Client) GetContext(ctx context.Context, url string) (Response, error) {
+	return c.do(ctx, http.MethodGet, url, http.NoBody)
 }
 
 func (c *Client) Post(url string, body any) (Response, error) {
+	return c.PostContext(context.Background(), url, body)
+}
+
+// PostContext is like Post but carries the context of the request being
+// handled, forwarding its request ID and trace to the called service.
+func (c *Client) PostContext(ctx context.Context, url string, body any) (Response, error) {
 	buff, err := json.Marshal(body)
 	if err != nil {
 		return Response{}, err
 	}
 
-	req, err := c.request(http.MethodPost, url, bytes.NewBuffer(buff))
+	return c.do(ctx, http.MethodPost, url, bytes.NewBuffer(buff))
+}
+
+// do sends the request inside a client span when the context is traced.
+func (c *Client) do(ctx context.Context, method, url string, body io.Reader) (Response, error) {
+	ctx, span := tracer.Start(ctx, "HTTP "+method, tracer.SpanKindClient)
+	defer span.End()
+
+	req, err := c.request(ctx, method, url, body)
 	if err != nil {
+		span.SetError(err)
 		return Response{}, err
 	}
+	span.SetAttributes("http.method", method, "url.full", req.URL.String())
 
 	res, err := c.http.Do(req)
 	if err != nil {
+		span.SetError(err)
 		return Response{}, err
 	}
 
 	defer res.Body.Close()
-
-	buff, err = io.ReadAll(res.Body)
+	buff, err := io.ReadAll(res.Body)
 	if err != nil {
+		span.SetError(err)
 		return Response{}, err
 	}
 
+	span.SetAttributes("http.status_code", res.StatusCode)
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(tracer.StatusError, http.StatusText(res.StatusCode))
+	}
+
 	response := Response{
 		StatusCode: res.StatusCode,
 		Body:       buff,
@@ -3,18 +3,60 @@ package web
 import (
 	"context"
 	"net/http"
+
+	"github.com/nutchapon-m/web-server/foundation/logger"
 )
 
 type ctxKey int
 
 const (
 	writerKey ctxKey = iota + 1
+	valuesKey
+	templatesKey
 )
 
+// values holds the state of a request that middleware can set and the
+// response encoders can read after the handlers have returned.
+type values struct {
+	nonce     string
+	csrfToken string
+	requestID string
+
+	// responded is set once the response has been written outside of
+	// Respond or the connection has been hijacked.
+	responded bool
+}
+
+func setValues(ctx context.Context) context.Context {
+	v := values{}
+
+	// Loggers read the request ID through the logger package so they can
+	// add it to every record.
+	ctx = logger.WithRequestIDFunc(ctx, func() string { return v.requestID })
+
+	return context.WithValue(ctx, valuesKey, &v)
+}
+
+func getValues(ctx context.Context) *values {
+	v, ok := ctx.Value(valuesKey).(*values)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
 func setWriter(ctx context.Context, w http.ResponseWriter) context.Context {
 	return context.WithValue(ctx, writerKey, w)
 }
 
+// SetWriter replaces the writer returned by GetWriter for the handlers that
+// are called with the returned context. Middleware uses it to wrap the
+// writer of streamed responses.
+func SetWriter(ctx context.Context, w http.ResponseWriter) context.Context {
+	return setWriter(ctx, w)
+}
+
 // GetWriter returns the underlying writer for the request.
 func GetWriter(ctx context.Context) http.ResponseWriter {
 	v, ok := ctx.Value(writerKey).(http.ResponseWriter)
@@ -25,6 +67,87 @@ func GetWriter(ctx context.Context) http.ResponseWriter {
 	return v
 }
 
+// SetNonce stores the Content-Security-Policy nonce for the request. The
+// nonce is also visible to the encoders of the response.
+func SetNonce(ctx context.Context, nonce string) context.Context {
+	v := getValues(ctx)
+	if v == nil {
+		ctx = setValues(ctx)
+		v = getValues(ctx)
+	}
+	v.nonce = nonce
+
+	return ctx
+}
+
+// Nonce returns the Content-Security-Policy nonce for the request so HTML
+// responses can mark their inline scripts and styles.
+func Nonce(ctx context.Context) string {
+	v := getValues(ctx)
+	if v == nil {
+		return ""
+	}
+
+	return v.nonce
+}
+
+// SetCSRFToken stores the CSRF token issued for the request so handlers can
+// embed it in the pages they render.
+func SetCSRFToken(ctx context.Context, token string) context.Context {
+	v := getValues(ctx)
+	if v == nil {
+		ctx = setValues(ctx)
+		v = getValues(ctx)
+	}
+	v.csrfToken = token
+
+	return ctx
+}
+
+// CSRFToken returns the CSRF token issued for the request.
+func CSRFToken(ctx context.Context) string {
+	v := getValues(ctx)
+	if v == nil {
+		return ""
+	}
+
+	return v.csrfToken
+}
+
+// RequestIDHeader is the header used to receive and forward request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// SetRequestID stores the ID of the request, which is added to every record
+// logged with the context. The first ID set for a request is visible for the
+// whole request, including the encoders of the response. Later calls, like
+// for a sub operation, only apply to the returned context.
+func SetRequestID(ctx context.Context, id string) context.Context {
+	if v := getValues(ctx); v != nil && v.requestID == "" {
+		v.requestID = id
+		return ctx
+	}
+
+	return logger.SetRequestID(ctx, id)
+}
+
+// RequestID returns the ID of the request.
+func RequestID(ctx context.Context) string {
+	return logger.RequestID(ctx)
+}
+
+func setTemplates(ctx context.Context, t *Templates) context.Context {
+	return context.WithValue(ctx, templatesKey, t)
+}
+
+func getTemplates(ctx context.Context) *Templates {
+	v, ok := ctx.Value(templatesKey).(*Templates)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
 func Set(ctx context.Context, key, val any) context.Context {
 	return context.WithValue(ctx, key, val)
 }
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nutchapon-m/web-server/foundation/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	// A parent shared by every request, like the BaseContext of a server,
+	// which already holds an ID.
+	base := logger.SetRequestID(context.Background(), "base")
+
+	first := SetRequestID(setValues(base), "first")
+	second := SetRequestID(setValues(base), "second")
+	sub := SetRequestID(first, "sub")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "base", ctx: base, want: "base"},
+		{name: "first", ctx: first, want: "first"},
+		{name: "second", ctx: second, want: "second"},
+		{name: "sub operation", ctx: sub, want: "sub"},
+		{name: "without values", ctx: SetRequestID(context.Background(), "plain"), want: "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequestID(tt.ctx); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestIDVisibleToParent(t *testing.T) {
+	// The context created by the app for the request sees the ID set by the
+	// middleware, so the encoders of the response can use it.
+	ctx := setValues(context.Background())
+	SetRequestID(context.WithValue(ctx, ctxKey(99), true), "req")
+
+	if got := RequestID(ctx); got != "req" {
+		t.Errorf("got %q, want req", got)
+	}
+}
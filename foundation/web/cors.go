@@ -0,0 +1,282 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy defines how cross-origin requests are answered.
+type CORSPolicy struct {
+	// AllowOrigins lists the origins that are allowed. An entry can be "*",
+	// an exact origin like "https://example.com" or contain a wildcard
+	// subdomain like "https://*.example.com".
+	AllowOrigins []string
+
+	// AllowOriginPatterns lists regular expressions an origin can match. The
+	// expressions are anchored to match the whole origin.
+	AllowOriginPatterns []string
+
+	// AllowMethods lists the methods allowed by a preflight request.
+	AllowMethods []string
+
+	// AllowHeaders lists the request headers allowed by a preflight request.
+	// The value "*" allows any header.
+	AllowHeaders []string
+
+	// ExposeHeaders lists the response headers the browser can read.
+	ExposeHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers. When set
+	// the request origin is echoed instead of "*" as the spec requires. It
+	// can't be combined with the "*" origin since any site could then make
+	// authenticated requests.
+	AllowCredentials bool
+
+	// MaxAge specifies how long the preflight response can be cached.
+	MaxAge time.Duration
+}
+
+// DefaultCORSPolicy returns a policy for the specified origins with the
+// methods and headers used by the services. Credentials are allowed unless
+// the origins contain "*".
+func DefaultCORSPolicy(origins ...string) CORSPolicy {
+	return CORSPolicy{
+		AllowOrigins: origins,
+		AllowMethods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodOptions,
+			http.MethodPost,
+			http.MethodPatch,
+			http.MethodPut,
+			http.MethodDelete,
+		},
+		AllowHeaders: []string{
+			"Accept",
+			"Content-Type",
+			"Content-Length",
+			"Accept-Encoding",
+			"X-CSRF-Token",
+			"Authorization",
+		},
+		AllowCredentials: !slices.Contains(origins, "*"),
+		MaxAge:           24 * time.Hour,
+	}
+}
+
+// =====================================================================================================================
+
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+// corsPolicy is the compiled form of a CORSPolicy that is used to answer
+// requests.
+type corsPolicy struct {
+	anyOrigin   bool
+	anyHeader   bool
+	origins     []string
+	wildcards   []wildcardOrigin
+	patterns    []*regexp.Regexp
+	methods     []string
+	headers     []string
+	expose      string
+	credentials bool
+	maxAge      string
+}
+
+// compile validates the policy and prepares it for matching. It panics if
+// an origin pattern is not a valid regular expression or if credentials are
+// allowed for any origin since a policy is only set during startup.
+func (p CORSPolicy) compile() *corsPolicy {
+	cp := corsPolicy{
+		credentials: p.AllowCredentials,
+		expose:      strings.Join(canonicalHeaders(p.ExposeHeaders), ", "),
+	}
+
+	for _, origin := range p.AllowOrigins {
+		switch {
+		case origin == "*":
+			cp.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(strings.ToLower(origin), "*")
+			cp.wildcards = append(cp.wildcards, wildcardOrigin{prefix: prefix, suffix: suffix})
+		default:
+			cp.origins = append(cp.origins, strings.ToLower(origin))
+		}
+	}
+
+	if cp.anyOrigin && cp.credentials {
+		panic(`cors: AllowCredentials can't be used with the "*" origin`)
+	}
+
+	for _, pattern := range p.AllowOriginPatterns {
+		cp.patterns = append(cp.patterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+
+	for _, method := range p.AllowMethods {
+		cp.methods = append(cp.methods, strings.ToUpper(method))
+	}
+
+	for _, header := range p.AllowHeaders {
+		if header == "*" {
+			cp.anyHeader = true
+			continue
+		}
+		cp.headers = append(cp.headers, http.CanonicalHeaderKey(header))
+	}
+
+	if p.MaxAge > 0 {
+		cp.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+
+	return &cp
+}
+
+// allowOrigin reports whether the origin is allowed by the policy.
+func (cp *corsPolicy) allowOrigin(origin string) bool {
+	if cp.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+
+	if slices.Contains(cp.origins, origin) {
+		return true
+	}
+
+	for _, wc := range cp.wildcards {
+		if len(origin) <= len(wc.prefix)+len(wc.suffix) {
+			continue
+		}
+		if !strings.HasPrefix(origin, wc.prefix) || !strings.HasSuffix(origin, wc.suffix) {
+			continue
+		}
+
+		// The wildcard only covers subdomain labels of the host.
+		sub := origin[len(wc.prefix) : len(origin)-len(wc.suffix)]
+		if !strings.ContainsAny(sub, "/:") {
+			return true
+		}
+	}
+
+	for _, re := range cp.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply sets the CORS headers for the request. It reports true when the
+// request was a preflight request that has been answered.
+func (cp *corsPolicy) apply(w http.ResponseWriter, r *http.Request) bool {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	// The response depends on the origin unless every origin receives the
+	// same answer.
+	if !cp.anyOrigin {
+		h.Add("Vary", "Origin")
+	}
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if origin == "" {
+		return false
+	}
+
+	if !cp.allowOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	allowOrigin := "*"
+	if !cp.anyOrigin {
+		allowOrigin = origin
+	}
+
+	if !preflight {
+		h.Set("Access-Control-Allow-Origin", allowOrigin)
+		if cp.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if cp.expose != "" {
+			h.Set("Access-Control-Expose-Headers", cp.expose)
+		}
+		return false
+	}
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !slices.Contains(cp.methods, method) {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	reqHeaders := requestHeaders(r)
+	if !cp.anyHeader {
+		for _, header := range reqHeaders {
+			if !slices.Contains(cp.headers, header) {
+				w.WriteHeader(http.StatusForbidden)
+				return true
+			}
+		}
+	}
+
+	h.Set("Access-Control-Allow-Origin", allowOrigin)
+	if cp.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(cp.methods, ", "))
+
+	// A literal "*" is not honored for credentialed requests so the
+	// requested headers are echoed back instead.
+	switch {
+	case cp.anyHeader && len(reqHeaders) > 0:
+		h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+	case len(cp.headers) > 0:
+		h.Set("Access-Control-Allow-Headers", strings.Join(cp.headers, ", "))
+	}
+
+	if cp.maxAge != "" {
+		h.Set("Access-Control-Max-Age", cp.maxAge)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// requestHeaders returns the canonical header names listed in the
+// Access-Control-Request-Headers header.
+func requestHeaders(r *http.Request) []string {
+	var headers []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(v, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+
+	return headers
+}
+
+func canonicalHeaders(headers []string) []string {
+	canonical := make([]string, len(headers))
+	for i, header := range headers {
+		canonical[i] = http.CanonicalHeaderKey(header)
+	}
+
+	return canonical
+}
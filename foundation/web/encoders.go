@@ -0,0 +1,201 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// XMLResponse is a simple encoder for returning XML payloads.
+type XMLResponse struct {
+	Status int
+	Data   any
+}
+
+// XML constructs an XML response.
+func XML(status int, data any) XMLResponse {
+	return XMLResponse{Status: status, Data: data}
+}
+
+func (x XMLResponse) HTTPStatus() int { return x.Status }
+
+func (x XMLResponse) Encode() ([]byte, string, error) {
+	if x.Data == nil {
+		return nil, "application/xml", nil
+	}
+
+	b, err := xml.Marshal(x.Data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return append([]byte(xml.Header), b...), "application/xml; charset=utf-8", nil
+}
+
+// =====================================================================================================================
+
+// TextResponse is a simple encoder for returning plain text. Data that is not
+// a string is formatted with the fmt package.
+type TextResponse struct {
+	Status int
+	Data   any
+}
+
+// Text constructs a plain text response.
+func Text(status int, data any) TextResponse {
+	return TextResponse{Status: status, Data: data}
+}
+
+func (t TextResponse) HTTPStatus() int { return t.Status }
+
+func (t TextResponse) Encode() ([]byte, string, error) {
+	if t.Data == nil {
+		return nil, "text/plain", nil
+	}
+
+	var s string
+	switch v := t.Data.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+
+	return []byte(s), "text/plain; charset=utf-8", nil
+}
+
+// =====================================================================================================================
+
+// CSVResponse encodes a slice of structs as CSV. The header row is taken from
+// the csv struct tag, falling back to the json tag and the field name. Fields
+// tagged with "-" are skipped. A [][]string is written as is.
+type CSVResponse struct {
+	Status int
+	Data   any
+}
+
+// CSV constructs a CSV response.
+func CSV(status int, data any) CSVResponse {
+	return CSVResponse{Status: status, Data: data}
+}
+
+func (c CSVResponse) HTTPStatus() int { return c.Status }
+
+func (c CSVResponse) Encode() ([]byte, string, error) {
+	if c.Data == nil {
+		return nil, "text/csv", nil
+	}
+
+	records, err := csvRecords(c.Data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.WriteAll(records); err != nil {
+		return nil, "", err
+	}
+
+	return b.Bytes(), "text/csv; charset=utf-8", nil
+}
+
+func csvRecords(data any) ([][]string, error) {
+	if records, ok := data.([][]string); ok {
+		return records, nil
+	}
+
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv: %T is not a slice", data)
+	}
+
+	et := rv.Type().Elem()
+	for et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: %T is not a slice of structs", data)
+	}
+
+	var header []string
+	var fields []int
+	for i := range et.NumField() {
+		sf := et.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := csvName(sf)
+		if name == "-" {
+			continue
+		}
+
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	records := make([][]string, 0, rv.Len()+1)
+	records = append(records, header)
+
+	for i := range rv.Len() {
+		ev := rv.Index(i)
+		for ev.Kind() == reflect.Pointer {
+			if ev.IsNil() {
+				break
+			}
+			ev = ev.Elem()
+		}
+
+		record := make([]string, len(fields))
+		if ev.Kind() == reflect.Struct {
+			for j, idx := range fields {
+				record[j] = csvValue(ev.Field(idx))
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func csvName(sf reflect.StructField) string {
+	for _, key := range []string{"csv", "json"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" {
+			return name
+		}
+	}
+
+	return sf.Name
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	case fmt.Stringer:
+		return x.String()
+	}
+
+	return fmt.Sprint(v.Interface())
+}
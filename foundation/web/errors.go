@@ -0,0 +1,57 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/nutchapon-m/web-server/foundation/validate"
+)
+
+// Error is returned by the package when a request can't be served, for
+// example when the body can't be decoded or a file doesn't exist. It carries
+// the HTTP status of the failure and, for invalid input, the fields that
+// failed so the app layer can map it to its own errors. It's an Encoder so
+// handlers can also return it as is.
+type Error struct {
+	Status  int                  `json:"-"`
+	Message string               `json:"message"`
+	Fields  validate.FieldErrors `json:"fields,omitempty"`
+}
+
+// NewError constructs an error with the HTTP status and message.
+func NewError(status int, format string, v ...any) *Error {
+	return &Error{
+		Status:  status,
+		Message: fmt.Sprintf(format, v...),
+	}
+}
+
+// newFieldErrors constructs a bad request error for the fields.
+func newFieldErrors(fields validate.FieldErrors) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Message: "invalid fields",
+		Fields:  fields,
+	}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if len(e.Fields) > 0 {
+		return e.Fields.Error()
+	}
+
+	return e.Message
+}
+
+// HTTPStatus implements the httpStatus interface.
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+// Encode implements the Encoder interface.
+func (e *Error) Encode() ([]byte, string, error) {
+	data, err := json.Marshal(e)
+	return data, "application/json", err
+}
@@ -0,0 +1,424 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/nutchapon-m/web-server/foundation/validate"
+)
+
+// FormOptions represent the limits applied while reading a form. The body
+// limit of the app or route still applies, see LimitBody.
+type FormOptions struct {
+	// MaxFileSize is the largest file accepted for a single part. Zero or
+	// less means no limit.
+	MaxFileSize int64
+
+	// MaxTotalSize is the largest size of all the parts together, or of the
+	// body of a url-encoded form. Zero or less means no limit.
+	MaxTotalSize int64
+
+	// MaxMemory is the number of bytes of a file kept in memory before the
+	// rest is written to a temporary file.
+	MaxMemory int64
+
+	// AllowedTypes lists the sniffed content types accepted for files. An
+	// entry ending in "/" matches a prefix like "image/". Empty allows all.
+	AllowedTypes []string
+}
+
+// DefaultFormOptions returns the limits used when none are provided.
+func DefaultFormOptions() FormOptions {
+	return FormOptions{
+		MaxFileSize:  10 << 20,
+		MaxTotalSize: 32 << 20,
+		MaxMemory:    1 << 20,
+	}
+}
+
+// maxFormValue is the largest value accepted for a non file part.
+const maxFormValue = 1 << 20
+
+// UploadedFile represents a file received in a multipart form. Small files
+// are held in memory and larger ones are stored in a temporary file.
+type UploadedFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Size        int64
+
+	data []byte
+	path string
+}
+
+// Open returns a reader for the content of the file.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	if f.path == "" {
+		return io.NopCloser(bytes.NewReader(f.data)), nil
+	}
+
+	return os.Open(f.path)
+}
+
+// Remove deletes the temporary file, if any.
+func (f *UploadedFile) Remove() error {
+	if f.path == "" {
+		return nil
+	}
+
+	err := os.Remove(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Form represents the values and files of a parsed form.
+type Form struct {
+	Values url.Values
+	Files  map[string][]*UploadedFile
+}
+
+// RemoveAll deletes the temporary files of the form. Handlers should defer
+// it once the files are no longer needed.
+func (f *Form) RemoveAll() error {
+	var errList []error
+	for _, files := range f.Files {
+		for _, file := range files {
+			if err := file.Remove(); err != nil {
+				errList = append(errList, err)
+			}
+		}
+	}
+
+	return errors.Join(errList...)
+}
+
+// =====================================================================================================================
+
+// ParseForm reads a multipart or url-encoded form from the request. Files
+// are streamed part by part so only MaxMemory bytes of each file are held in
+// memory. Oversized or rejected files are reported as an *Error with the
+// failed fields and exceeding MaxTotalSize as an *Error with the 413 status.
+func ParseForm(r *http.Request, opts FormOptions) (*Form, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, NewError(http.StatusUnsupportedMediaType, "Content-Type must be multipart/form-data or application/x-www-form-urlencoded")
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if opts.MaxTotalSize > 0 {
+			r.Body = http.MaxBytesReader(nil, r.Body, opts.MaxTotalSize)
+		}
+		if err := r.ParseForm(); err != nil {
+			return nil, readError(err)
+		}
+		return &Form{Values: r.PostForm, Files: map[string][]*UploadedFile{}}, nil
+
+	case "multipart/form-data":
+		return parseMultipart(r, opts)
+	}
+
+	return nil, NewError(http.StatusUnsupportedMediaType, "Content-Type must be multipart/form-data or application/x-www-form-urlencoded")
+}
+
+func parseMultipart(r *http.Request, opts FormOptions) (*Form, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, "invalid multipart form: %s", err)
+	}
+
+	form := Form{
+		Values: url.Values{},
+		Files:  map[string][]*UploadedFile{},
+	}
+	var fieldErrs validate.FieldErrors
+
+	fail := func(err error) (*Form, error) {
+		form.RemoveAll()
+		return nil, err
+	}
+
+	var total int64
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(readError(err))
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		// A negative remaining size means the form has no total limit.
+		remaining := int64(-1)
+		if opts.MaxTotalSize > 0 {
+			remaining = opts.MaxTotalSize - total
+		}
+
+		if part.FileName() == "" {
+			limit := int64(maxFormValue)
+			if remaining >= 0 {
+				limit = min(remaining, maxFormValue)
+			}
+			value, err := io.ReadAll(io.LimitReader(part, limit+1))
+			part.Close()
+			if err != nil {
+				return fail(readError(err))
+			}
+			if int64(len(value)) > limit {
+				if limit == remaining {
+					return fail(NewError(http.StatusRequestEntityTooLarge, "form must not be larger than %d bytes", opts.MaxTotalSize))
+				}
+				fieldErrs.Add(name, fmt.Errorf("value must not be larger than %d bytes", maxFormValue))
+				continue
+			}
+
+			total += int64(len(value))
+			form.Values.Add(name, string(value))
+			continue
+		}
+
+		file, err := readFilePart(part, opts, remaining)
+		part.Close()
+		switch {
+		case errors.Is(err, errTotalExceeded):
+			return fail(NewError(http.StatusRequestEntityTooLarge, "form must not be larger than %d bytes", opts.MaxTotalSize))
+		case errors.Is(err, errFileExceeded):
+			fieldErrs.Add(name, fmt.Errorf("file must not be larger than %d bytes", opts.MaxFileSize))
+			continue
+		case errors.Is(err, errTypeRejected):
+			fieldErrs.Add(name, fmt.Errorf("file type %s is not allowed", file.ContentType))
+			continue
+		case err != nil:
+			return fail(readError(err))
+		}
+
+		total += file.Size
+		form.Files[name] = append(form.Files[name], file)
+	}
+
+	if len(fieldErrs) > 0 {
+		return fail(newFieldErrors(fieldErrs))
+	}
+
+	return &form, nil
+}
+
+var (
+	errTotalExceeded = errors.New("form too large")
+	errFileExceeded  = errors.New("file too large")
+	errTypeRejected  = errors.New("file type rejected")
+)
+
+// readFilePart reads a file part, sniffing its content type from the first
+// bytes and spilling to a temporary file once MaxMemory is reached. A
+// negative remaining size means the form has no total limit.
+func readFilePart(part *multipart.Part, opts FormOptions, remaining int64) (*UploadedFile, error) {
+	file := UploadedFile{
+		Field:    part.FormName(),
+		Filename: part.FileName(),
+	}
+
+	limit := remaining
+	if opts.MaxFileSize > 0 && (limit < 0 || opts.MaxFileSize < limit) {
+		limit = opts.MaxFileSize
+	}
+
+	// One byte past the limit is read so an oversized file can be detected.
+	var lr io.Reader = part
+	if limit >= 0 {
+		lr = io.LimitReader(part, limit+1)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(lr, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return &file, err
+	}
+	head = head[:n]
+
+	file.ContentType = http.DetectContentType(head)
+	if !allowedType(file.ContentType, opts.AllowedTypes) {
+		return &file, errTypeRejected
+	}
+
+	var buf bytes.Buffer
+	buf.Write(head)
+
+	memory := opts.MaxMemory
+	if memory <= 0 {
+		memory = DefaultFormOptions().MaxMemory
+	}
+
+	if _, err := io.CopyN(&buf, lr, memory-int64(buf.Len())+1); err != nil && !errors.Is(err, io.EOF) {
+		return &file, err
+	}
+
+	if int64(buf.Len()) <= memory {
+		file.data = buf.Bytes()
+		file.Size = int64(buf.Len())
+		return &file, sizeError(file.Size, limit, remaining)
+	}
+
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return &file, err
+	}
+	defer tmp.Close()
+	file.path = tmp.Name()
+
+	size, err := io.Copy(tmp, io.MultiReader(&buf, lr))
+	if err != nil {
+		file.Remove()
+		return &file, err
+	}
+	file.Size = size
+
+	if err := sizeError(size, limit, remaining); err != nil {
+		file.Remove()
+		return &file, err
+	}
+
+	return &file, nil
+}
+
+func sizeError(size int64, limit int64, remaining int64) error {
+	if limit < 0 || size <= limit {
+		return nil
+	}
+
+	if limit == remaining {
+		return errTotalExceeded
+	}
+
+	return errFileExceeded
+}
+
+func allowedType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, a := range allowed {
+		if mediaType == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(mediaType, a)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// =====================================================================================================================
+
+var (
+	uploadedFileType  = reflect.TypeFor[*UploadedFile]()
+	uploadedFilesType = reflect.TypeFor[[]*UploadedFile]()
+)
+
+// BindForm parses the form and fills a value of type T, which must be a
+// struct, based on the form struct tags. Fields of type *UploadedFile or
+// []*UploadedFile receive the files of the part. The required option reports
+// missing parts:
+//
+//	type Upload struct {
+//		Title  string             `form:"title,required"`
+//		Tags   []string           `form:"tag"`
+//		Avatar *web.UploadedFile  `form:"avatar,required"`
+//	}
+//
+// The returned form must be removed with RemoveAll once the files are no
+// longer needed.
+func BindForm[T any](r *http.Request, opts FormOptions) (T, *Form, error) {
+	var v T
+
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return v, nil, fmt.Errorf("bind form: %T is not a struct", v)
+	}
+
+	form, err := ParseForm(r, opts)
+	if err != nil {
+		return v, nil, err
+	}
+
+	var fieldErrs validate.FieldErrors
+	bindForm(form, rv, &fieldErrs)
+
+	if len(fieldErrs) > 0 {
+		form.RemoveAll()
+		return v, nil, newFieldErrors(fieldErrs)
+	}
+
+	return v, form, nil
+}
+
+func bindForm(form *Form, rv reflect.Value, fieldErrs *validate.FieldErrors) {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		field := rv.Field(i)
+
+		tag, ok := sf.Tag.Lookup("form")
+		if !ok {
+			if field.Kind() == reflect.Struct && field.Type() != timeType {
+				bindForm(form, field, fieldErrs)
+			}
+			continue
+		}
+
+		name, opt, _ := strings.Cut(tag, ",")
+		required := opt == "required"
+
+		switch field.Type() {
+		case uploadedFileType, uploadedFilesType:
+			files := form.Files[name]
+			if len(files) == 0 {
+				if required {
+					fieldErrs.Add(name, errors.New("is required"))
+				}
+				continue
+			}
+
+			if field.Type() == uploadedFileType {
+				field.Set(reflect.ValueOf(files[0]))
+			} else {
+				field.Set(reflect.ValueOf(files))
+			}
+			continue
+		}
+
+		values := form.Values[name]
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if required {
+				fieldErrs.Add(name, errors.New("is required"))
+			}
+			continue
+		}
+
+		if err := setValue(field, values); err != nil {
+			fieldErrs.Add(name, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Group represents a set of routes that share a path prefix and a set of
+// middleware. Groups can be nested and inherit the prefix and middleware of
+// their parent in order.
+type Group struct {
+	app    *App
+	prefix string
+	mw     []MidFunc
+}
+
+// Group constructs a route group rooted at the specified prefix. The
+// middleware provided is executed after the application middleware and
+// before any route specific middleware.
+func (a *App) Group(prefix string, mw ...MidFunc) *Group {
+	return &Group{
+		app:    a,
+		prefix: prefix,
+		mw:     mw,
+	}
+}
+
+// Group constructs a nested route group. The prefix is appended to the
+// parent prefix and the middleware is executed after the parent middleware.
+func (g *Group) Group(prefix string, mw ...MidFunc) *Group {
+	return &Group{
+		app:    g.app,
+		prefix: g.prefix + prefix,
+		mw:     slices.Concat(g.mw, mw),
+	}
+}
+
+// Prefix returns the full path prefix for the group.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// CORS sets a CORS policy for the path inside the group that overrides the
+// application policy.
+func (g *Group) CORS(path string, policy CORSPolicy) {
+	g.app.RouteCORS(g.prefix+path, policy)
+}
+
+// HandlerFunc sets a handler function for a given HTTP method and path pair
+// inside the group.
+func (g *Group) HandlerFunc(method, path string, handler HandlerFunc, mw ...MidFunc) {
+	g.app.HandlerFunc(method, g.prefix, path, handler, slices.Concat(g.mw, mw)...)
+}
+
+// Get registers a handler for GET requests on the path.
+func (g *Group) Get(path string, handler HandlerFunc, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodGet, path, handler, mw...)
+}
+
+// Post registers a handler for POST requests on the path.
+func (g *Group) Post(path string, handler HandlerFunc, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodPost, path, handler, mw...)
+}
+
+// Put registers a handler for PUT requests on the path.
+func (g *Group) Put(path string, handler HandlerFunc, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodPut, path, handler, mw...)
+}
+
+// Patch registers a handler for PATCH requests on the path.
+func (g *Group) Patch(path string, handler HandlerFunc, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodPatch, path, handler, mw...)
+}
+
+// Delete registers a handler for DELETE requests on the path.
+func (g *Group) Delete(path string, handler HandlerFunc, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodDelete, path, handler, mw...)
+}
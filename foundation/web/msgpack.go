@@ -0,0 +1,264 @@
+package web
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MsgPackResponse is an encoder for returning MessagePack payloads. Structs
+// are encoded as maps using the msgpack struct tag, falling back to the json
+// tag and the field name.
+type MsgPackResponse struct {
+	Status int
+	Data   any
+}
+
+// MsgPack constructs a MessagePack response.
+func MsgPack(status int, data any) MsgPackResponse {
+	return MsgPackResponse{Status: status, Data: data}
+}
+
+func (m MsgPackResponse) HTTPStatus() int { return m.Status }
+
+func (m MsgPackResponse) Encode() ([]byte, string, error) {
+	if m.Data == nil {
+		return nil, "application/msgpack", nil
+	}
+
+	b, err := appendMsgPack(nil, reflect.ValueOf(m.Data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return b, "application/msgpack", nil
+}
+
+// =====================================================================================================================
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+func appendMsgPack(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, 0xc0), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgPack(b, v.Elem())
+	}
+
+	if v.Type() == timeType {
+		return appendMsgPackString(b, v.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgPackString(b, string(text)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgPackInt(b, v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendMsgPackUint(b, v.Uint()), nil
+
+	case reflect.Float32:
+		b = append(b, 0xca)
+		return binary.BigEndian.AppendUint32(b, math.Float32bits(float32(v.Float()))), nil
+
+	case reflect.Float64:
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v.Float())), nil
+
+	case reflect.String:
+		return appendMsgPackString(b, v.String()), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice && v.IsNil() {
+				return append(b, 0xc0), nil
+			}
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return appendMsgPackBin(b, data), nil
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+
+		b = appendMsgPackHeader(b, v.Len(), 0x90, 0xdc, 0xdd)
+		for i := range v.Len() {
+			var err error
+			if b, err = appendMsgPack(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+
+		// Keys are sorted so the output is deterministic.
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		b = appendMsgPackHeader(b, len(keys), 0x80, 0xde, 0xdf)
+		for _, key := range keys {
+			var err error
+			if b, err = appendMsgPack(b, key); err != nil {
+				return nil, err
+			}
+			if b, err = appendMsgPack(b, v.MapIndex(key)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	case reflect.Struct:
+		return appendMsgPackStruct(b, v)
+	}
+
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+func appendMsgPackStruct(b []byte, v reflect.Value) ([]byte, error) {
+	type field struct {
+		name  string
+		value reflect.Value
+	}
+
+	var fields []field
+	for i := range v.NumField() {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("msgpack")
+		if tag == "" {
+			tag = sf.Tag.Get("json")
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+
+		fields = append(fields, field{name: name, value: v.Field(i)})
+	}
+
+	b = appendMsgPackHeader(b, len(fields), 0x80, 0xde, 0xdf)
+	for _, f := range fields {
+		b = appendMsgPackString(b, f.name)
+
+		var err error
+		if b, err = appendMsgPack(b, f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// appendMsgPackHeader writes the header of an array or map using the fix,
+// 16 bit or 32 bit format depending on the length.
+func appendMsgPackHeader(b []byte, n int, fix, f16, f32 byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, f16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, f32), uint32(n))
+	}
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, s...)
+}
+
+func appendMsgPackBin(b []byte, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+
+	return append(b, data...)
+}
+
+func appendMsgPackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return appendMsgPackUint(b, uint64(n))
+	case n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+func appendMsgPackUint(b []byte, n uint64) []byte {
+	switch {
+	case n <= 127:
+		return append(b, byte(n))
+	case n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), n)
+	}
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EncoderFunc constructs an encoder for the payload of a response.
+type EncoderFunc func(status int, data any) Encoder
+
+type registeredEncoder struct {
+	mediaType string
+	fn        EncoderFunc
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = []registeredEncoder{
+		{mediaType: "application/json", fn: func(status int, data any) Encoder { return JSON(status, data) }},
+		{mediaType: "application/xml", fn: func(status int, data any) Encoder { return XML(status, data) }},
+		{mediaType: "text/xml", fn: func(status int, data any) Encoder { return XML(status, data) }},
+		{mediaType: "text/csv", fn: func(status int, data any) Encoder { return CSV(status, data) }},
+		{mediaType: "application/msgpack", fn: func(status int, data any) Encoder { return MsgPack(status, data) }},
+		{mediaType: "application/x-msgpack", fn: func(status int, data any) Encoder { return MsgPack(status, data) }},
+		{mediaType: "text/plain", fn: func(status int, data any) Encoder { return Text(status, data) }},
+	}
+)
+
+// RegisterEncoder adds an encoder for the media type that can be selected by
+// Negotiate. Registering an existing media type replaces its encoder. When
+// the client has no preference the first registered encoder is used.
+func RegisterEncoder(mediaType string, fn EncoderFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i, enc := range encoders {
+		if enc.mediaType == mediaType {
+			encoders[i].fn = fn
+			return
+		}
+	}
+
+	encoders = append(encoders, registeredEncoder{mediaType: mediaType, fn: fn})
+}
+
+// Negotiate inspects the Accept header of the request and returns the
+// registered encoder the client prefers for the payload. When nothing
+// matches an *Error with the 406 status is returned.
+func Negotiate(ctx context.Context, r *http.Request, status int, data any) Encoder {
+	if w := GetWriter(ctx); w != nil {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	ranges := parseAccept(r.Header.Get("Accept"))
+	if len(ranges) == 0 {
+		return encoders[0].fn(status, data)
+	}
+
+	var best EncoderFunc
+	var bestQ float64
+	for _, enc := range encoders {
+		if q := acceptQuality(ranges, enc.mediaType); q > bestQ {
+			best, bestQ = enc.fn, q
+		}
+	}
+
+	if best == nil {
+		return NewError(http.StatusNotAcceptable, "none of the accepted media types [%s] are supported", r.Header.Get("Accept"))
+	}
+
+	return best(status, data)
+}
+
+// =====================================================================================================================
+
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses the media ranges of an Accept header.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, exists := params["q"]; exists {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching the
+// media type.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	q := 0.0
+	specificity := -1
+	for _, mr := range ranges {
+		var s int
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+
+	return q
+}
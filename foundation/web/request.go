@@ -1,9 +1,16 @@
 package web
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
+
+	"github.com/nutchapon-m/web-server/foundation/validate"
 )
 
 // Param returns the web call parameters from the request.
@@ -21,28 +28,164 @@ type validator interface {
 }
 
 // Decode reads the body of an HTTP request and decodes the body into the
-// specified data model. If the data model implements the validator interface,
-// the method will be called.
-func Decode(r *http.Request, v Decoder) error {
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		return fmt.Errorf("request: unable to read payload: %w", err)
-	}
+// specified data model. If the data model implements the Decoder interface
+// the raw body is passed to it, otherwise the body must be a single JSON
+// value with a JSON content type and no unknown fields. If the data model
+// implements the validator interface, the method will be called, otherwise
+// the validate struct tags of the model are checked.
+//
+// Errors are returned as an *Error so they can be returned by the handler as
+// is.
+func Decode(r *http.Request, v any) error {
+	if d, ok := v.(Decoder); ok {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return readError(err)
+		}
 
-	if err := v.Decode(data); err != nil {
-		return fmt.Errorf("request: decode: %w", err)
+		if err := d.Decode(data); err != nil {
+			return NewError(http.StatusBadRequest, "request: decode: %s", err)
+		}
+	} else {
+		if err := decodeJSON(r, v); err != nil {
+			return err
+		}
 	}
 
 	if v, ok := v.(validator); ok {
-		if err := v.Validate(); err != nil {
-			return err
+		return v.Validate()
+	}
+
+	var fieldErrs validate.FieldErrors
+	if err := validate.Check(v); errors.As(err, &fieldErrs) {
+		return newFieldErrors(fieldErrs)
+	}
+
+	return nil
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	if !isJSON(r.Header.Get("Content-Type")) {
+		return NewError(http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return jsonError(err)
+	}
+
+	// The body must only contain a single JSON value.
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return readError(err)
 		}
+		return NewError(http.StatusBadRequest, "request body must only contain a single JSON value")
 	}
 
 	return nil
 }
 
-// Get CSRF token from request cookies
+// jsonError converts the errors of the JSON decoder into errors that can be
+// returned to the client.
+func jsonError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxErr):
+		return readError(err)
+
+	case errors.Is(err, io.EOF):
+		return NewError(http.StatusBadRequest, "request body must not be empty")
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewError(http.StatusBadRequest, "request body contains malformed JSON")
+
+	case errors.As(err, &syntaxErr):
+		return NewError(http.StatusBadRequest, "request body contains malformed JSON at offset %d", syntaxErr.Offset)
+
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return newFieldErrors(validate.FieldErrors{{
+			Field: field,
+			Err:   fmt.Sprintf("must be of type %s", typeErr.Type),
+		}})
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return newFieldErrors(validate.FieldErrors{{
+			Field: field,
+			Err:   "unknown field",
+		}})
+	}
+
+	return NewError(http.StatusBadRequest, "request: decode: %s", err)
+}
+
+func readError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return NewError(http.StatusRequestEntityTooLarge, "request body must not be larger than %d bytes", maxErr.Limit)
+	}
+
+	return NewError(http.StatusBadRequest, "request: unable to read payload: %s", err)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// =============================================================================
+
+// limitedBody remembers the original body so a route can replace the limit
+// of the application.
+type limitedBody struct {
+	io.ReadCloser
+	orig io.ReadCloser
+}
+
+func limitBody(w http.ResponseWriter, r *http.Request, n int64) {
+	body := r.Body
+	if lb, ok := body.(*limitedBody); ok {
+		body = lb.orig
+	}
+
+	r.Body = &limitedBody{
+		ReadCloser: http.MaxBytesReader(w, body, n),
+		orig:       body,
+	}
+}
+
+// LimitBody sets the maximum number of bytes that can be read from the
+// request body of a route, replacing the limit of the application.
+func LimitBody(n int64) MidFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) Encoder {
+			if w := GetWriter(ctx); w != nil && r.Body != nil {
+				limitBody(w, r, n)
+			}
+
+			return next(ctx, r)
+		}
+	}
+}
+
+// =============================================================================
+
+// CSRF returns the CSRF token the client sent in the csrftoken cookie. Use
+// CSRFToken to get the token issued while handling the request.
 func CSRF(r *http.Request) (string, error) {
 	tok, err := r.Cookie("csrftoken")
 	if err != nil {
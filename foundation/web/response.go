@@ -1,10 +1,12 @@
 package web
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 )
 
@@ -72,12 +74,46 @@ type httpStatus interface {
 	HTTPStatus() int
 }
 
+// Streamer is implemented by encoders that write their payload directly to
+// the response instead of returning it from Encode, so large payloads are
+// never held in memory. Respond calls Stream instead of Encode.
+type Streamer interface {
+	Encoder
+	Stream(ctx context.Context, w http.ResponseWriter) error
+}
+
+// Hijack takes over the connection of the request, for example to upgrade
+// it to another protocol. Respond does nothing once the connection has been
+// hijacked, so the handler can still return an error for the middleware to
+// log.
+func Hijack(ctx context.Context) (net.Conn, *bufio.ReadWriter, error) {
+	w := GetWriter(ctx)
+	if w == nil {
+		return nil, nil, errors.New("hijack: response writer not available")
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if v := getValues(ctx); v != nil {
+		v.responded = true
+	}
+
+	return conn, brw, nil
+}
+
 // Respond sends a response to the client.
 func Respond(ctx context.Context, w http.ResponseWriter, resp Encoder) error {
 	if _, ok := resp.(NoResponse); ok {
 		return nil
 	}
 
+	if v := getValues(ctx); v != nil && v.responded {
+		return nil
+	}
+
 	// If the context has been canceled, it means the client is no longer
 	// waiting for a response.
 	if err := ctx.Err(); err != nil {
@@ -86,6 +122,13 @@ func Respond(ctx context.Context, w http.ResponseWriter, resp Encoder) error {
 		}
 	}
 
+	if s, ok := resp.(Streamer); ok {
+		if err := s.Stream(ctx, w); err != nil {
+			return fmt.Errorf("respond: stream: %w", err)
+		}
+		return nil
+	}
+
 	statusCode := http.StatusOK
 
 	switch v := resp.(type) {
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Event represents a single Server-Sent Event. Data that is not a string or
+// []byte is encoded as JSON.
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// encode writes the event in the text/event-stream format.
+func (e Event) encode(b *bytes.Buffer) error {
+	if e.ID != "" {
+		fmt.Fprintf(b, "id: %s\n", sanitizeSSE(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(b, "event: %s\n", sanitizeSSE(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+
+	var data string
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		d, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("sse: encode data: %w", err)
+		}
+		data = string(d)
+	}
+
+	// A lone \r also ends a line in an event stream, so it's normalized too
+	// or data could inject fields.
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(b, "data: %s\n", line)
+	}
+	b.WriteByte('\n')
+
+	return nil
+}
+
+func sanitizeSSE(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEOptions represent optional parameters for a Server-Sent Events stream.
+type SSEOptions struct {
+	keepAlive time.Duration
+	retry     time.Duration
+}
+
+// WithSSEKeepAlive sets how often a comment is sent when there are no
+// events so proxies don't close the connection. The default is 15 seconds.
+func WithSSEKeepAlive(d time.Duration) func(opts *SSEOptions) {
+	return func(opts *SSEOptions) {
+		opts.keepAlive = d
+	}
+}
+
+// WithSSERetry sets the reconnection delay sent to the client when the
+// stream starts.
+func WithSSERetry(d time.Duration) func(opts *SSEOptions) {
+	return func(opts *SSEOptions) {
+		opts.retry = d
+	}
+}
+
+// LastEventID returns the id of the last event the client received before
+// reconnecting so the producer can resume the stream after it.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+
+	return r.URL.Query().Get("lastEventId")
+}
+
+// SSE streams the events to the client as Server-Sent Events, flushing after
+// every event, until the channel is closed or the request context is
+// canceled. The stream is written inside the handler so middleware like
+// mid.Logger observe its full duration. It returns NoResponse once the
+// stream ends. An event that can't be encoded ends the stream and its error
+// is returned for the middleware to log; Respond writes nothing else.
+//
+//	func (h *handlers) stream(ctx context.Context, r *http.Request) web.Encoder {
+//		events := h.bus.Subscribe(ctx, web.LastEventID(r))
+//		return web.SSE(ctx, events)
+//	}
+func SSE(ctx context.Context, events <-chan Event, options ...func(opts *SSEOptions)) Encoder {
+	opts := SSEOptions{
+		keepAlive: 15 * time.Second,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	w := GetWriter(ctx)
+	if w == nil {
+		return NewError(http.StatusInternalServerError, "sse: response writer not available")
+	}
+
+	rc := http.NewResponseController(w)
+
+	// Streams outlive the write timeout of the server.
+	rc.SetWriteDeadline(time.Time{})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if v := getValues(ctx); v != nil {
+		v.responded = true
+	}
+
+	if opts.retry > 0 {
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", opts.retry.Milliseconds()); err != nil {
+			return NewNoResponse()
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return NewNoResponse()
+	}
+
+	var keepAlive <-chan time.Time
+	if opts.keepAlive > 0 {
+		ticker := time.NewTicker(opts.keepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	var b bytes.Buffer
+	for {
+		select {
+		case <-ctx.Done():
+			return NewNoResponse()
+
+		case <-keepAlive:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return NewNoResponse()
+			}
+
+		case ev, ok := <-events:
+			if !ok {
+				return NewNoResponse()
+			}
+
+			b.Reset()
+			if err := ev.encode(&b); err != nil {
+				return NewError(http.StatusInternalServerError, "%s", err)
+			}
+
+			if _, err := w.Write(b.Bytes()); err != nil {
+				return NewNoResponse()
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return NewNoResponse()
+		}
+	}
+}
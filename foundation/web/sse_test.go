@@ -0,0 +1,61 @@
+package web
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEventEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "fields",
+			event: Event{ID: "1", Event: "update", Retry: time.Second, Data: "hello"},
+			want:  "id: 1\nevent: update\nretry: 1000\ndata: hello\n\n",
+		},
+		{
+			name:  "json",
+			event: Event{Data: map[string]int{"n": 1}},
+			want:  "data: {\"n\":1}\n\n",
+		},
+		{
+			name:  "multiline",
+			event: Event{Data: "a\r\nb\nc"},
+			want:  "data: a\ndata: b\ndata: c\n\n",
+		},
+		{
+			name:  "carriage return in data",
+			event: Event{Data: "a\rid: x\revent: evil"},
+			want:  "data: a\ndata: id: x\ndata: event: evil\n\n",
+		},
+		{
+			name:  "line breaks in fields",
+			event: Event{ID: "1\r\nevent: evil", Event: "a\rb", Data: []byte("x")},
+			want:  "id: 1event: evil\nevent: ab\ndata: x\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.event.encode(&b); err != nil {
+				t.Fatalf("encode: %s", err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventEncodeError(t *testing.T) {
+	var b bytes.Buffer
+	if err := (Event{Data: func() {}}).encode(&b); err == nil {
+		t.Error("got nil error, want an error for data that can't be encoded")
+	}
+}
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// StaticOptions represent the configuration for serving static assets.
+type StaticOptions struct {
+	// Index is the file served for directories. Defaults to index.html.
+	Index string
+
+	// SPA serves the root index for paths that don't match a file and have
+	// no extension so client side routing works on reload.
+	SPA bool
+
+	// Precompressed serves name.gz in place of name when it exists and the
+	// client accepts gzip.
+	Precompressed bool
+
+	// CacheControl returns the Cache-Control header for a file. By default
+	// HTML files must be revalidated and other assets are cached for a day.
+	CacheControl func(name string) string
+}
+
+func defaultCacheControl(name string) string {
+	if strings.HasSuffix(name, ".html") {
+		return "no-cache"
+	}
+
+	return "public, max-age=86400"
+}
+
+// Static serves the files of the file system under the prefix. The files can
+// come from an embed.FS or os.DirFS. Directory listings are never served and
+// the application middleware is applied like any other route.
+//
+// The files are registered as a GET route for every path under the prefix,
+// so routes registered for more specific paths take precedence and other
+// methods under the prefix receive a 405 with an Allow header.
+func (a *App) Static(prefix string, fsys fs.FS, opts StaticOptions, mw ...MidFunc) {
+	a.HandlerFunc(http.MethodGet, strings.TrimSuffix(prefix, "/"), "/{path...}", staticHandler(fsys, opts), mw...)
+}
+
+// Static serves the files of the file system under the prefix inside the
+// group.
+func (g *Group) Static(prefix string, fsys fs.FS, opts StaticOptions, mw ...MidFunc) {
+	g.HandlerFunc(http.MethodGet, strings.TrimSuffix(prefix, "/")+"/{path...}", staticHandler(fsys, opts), mw...)
+}
+
+func staticHandler(fsys fs.FS, opts StaticOptions) HandlerFunc {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+	if opts.CacheControl == nil {
+		opts.CacheControl = defaultCacheControl
+	}
+
+	return func(ctx context.Context, r *http.Request) Encoder {
+		name := strings.TrimPrefix(path.Clean("/"+r.PathValue("path")), "/")
+		if name == "" {
+			name = "."
+		}
+
+		resolved, info, err := resolveStatic(fsys, name, opts.Index)
+		if err != nil && opts.SPA && path.Ext(name) == "" {
+			resolved, info, err = resolveStatic(fsys, opts.Index, opts.Index)
+		}
+		if err != nil {
+			return NewError(http.StatusNotFound, "file %s not found", r.URL.Path)
+		}
+
+		w := GetWriter(ctx)
+
+		if opts.Precompressed && acceptsGzip(r) {
+			if f, err := fsys.Open(resolved + ".gz"); err == nil {
+				if gz, gzInfo, ok := seekableFile(f); ok {
+					if w != nil {
+						w.Header().Set("Content-Encoding", "gzip")
+						w.Header().Add("Vary", "Accept-Encoding")
+						w.Header().Set("Cache-Control", opts.CacheControl(resolved))
+					}
+					return FileResponse{r: r, name: resolved, modTime: gzInfo.ModTime(), content: gz}
+				}
+			}
+		}
+
+		f, err := fsys.Open(resolved)
+		if err != nil {
+			return NewError(http.StatusNotFound, "file %s not found", r.URL.Path)
+		}
+
+		content, _, ok := seekableFile(f)
+		if !ok {
+			return NewError(http.StatusInternalServerError, "file %s is not seekable", resolved)
+		}
+
+		if w != nil {
+			if opts.Precompressed {
+				w.Header().Add("Vary", "Accept-Encoding")
+			}
+			w.Header().Set("Cache-Control", opts.CacheControl(resolved))
+		}
+
+		return FileResponse{r: r, name: resolved, modTime: info.ModTime(), content: content}
+	}
+}
+
+// resolveStatic returns the file to serve for the name, using the index
+// file for directories.
+func resolveStatic(fsys fs.FS, name string, index string) (string, fs.FileInfo, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if info.IsDir() {
+		name = path.Join(name, index)
+		if info, err = fs.Stat(fsys, name); err != nil {
+			return "", nil, err
+		}
+		if info.IsDir() {
+			return "", nil, errors.New("index is a directory")
+		}
+	}
+
+	return name, info, nil
+}
+
+// seekableFile returns the file as a io.ReadSeeker, closing it when it can't
+// be served.
+func seekableFile(f fs.File) (io.ReadSeeker, fs.FileInfo, bool) {
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, nil, false
+	}
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		f.Close()
+		return nil, nil, false
+	}
+
+	return rs, info, true
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+
+	return false
+}
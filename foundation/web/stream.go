@@ -0,0 +1,209 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// StreamResponse writes the body to the client as it is produced.
+type StreamResponse struct {
+	Status      int
+	ContentType string
+	Body        io.WriterTo
+}
+
+// Stream constructs a response that copies the reader to the client. The
+// reader is closed once copied if it implements io.Closer.
+func Stream(status int, contentType string, r io.Reader) StreamResponse {
+	return StreamResponse{Status: status, ContentType: contentType, Body: readerTo{r: r}}
+}
+
+// StreamFunc constructs a response that calls fn to write the body, which is
+// useful to generate large exports without buffering them.
+func StreamFunc(status int, contentType string, fn func(w io.Writer) error) StreamResponse {
+	return StreamResponse{Status: status, ContentType: contentType, Body: writerFunc(fn)}
+}
+
+func (s StreamResponse) HTTPStatus() int { return s.Status }
+
+// Encode implements the Encoder interface by buffering the body. It's only
+// used when the response is not streamed.
+func (s StreamResponse) Encode() ([]byte, string, error) {
+	var b bytes.Buffer
+	if _, err := s.Body.WriteTo(&b); err != nil {
+		return nil, "", err
+	}
+
+	return b.Bytes(), s.ContentType, nil
+}
+
+// Stream implements the Streamer interface.
+func (s StreamResponse) Stream(ctx context.Context, w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", s.ContentType)
+	w.WriteHeader(s.Status)
+
+	if _, err := s.Body.WriteTo(w); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type readerTo struct {
+	r io.Reader
+}
+
+func (rt readerTo) WriteTo(w io.Writer) (int64, error) {
+	if c, ok := rt.r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	return io.Copy(w, rt.r)
+}
+
+type writerFunc func(w io.Writer) error
+
+func (fn writerFunc) WriteTo(w io.Writer) (int64, error) {
+	cw := countWriter{w: w}
+	err := fn(&cw)
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// =====================================================================================================================
+
+// FileResponse serves seekable content with support for byte ranges,
+// conditional requests answered with 304 and content type detection.
+type FileResponse struct {
+	r           *http.Request
+	name        string
+	modTime     time.Time
+	content     io.ReadSeeker
+	etag        string
+	disposition string
+}
+
+// File constructs a response that serves the content inline. The name is
+// used to detect the content type and the modification time is used for
+// the Last-Modified header. A weak ETag is derived from the modification
+// time and size unless WithETag is used.
+func File(r *http.Request, name string, modTime time.Time, content io.ReadSeeker) FileResponse {
+	return FileResponse{
+		r:           r,
+		name:        name,
+		modTime:     modTime,
+		content:     content,
+		disposition: "inline",
+	}
+}
+
+// Download constructs a response that serves the content as an attachment
+// so browsers save it with the name.
+func Download(r *http.Request, name string, modTime time.Time, content io.ReadSeeker) FileResponse {
+	f := File(r, name, modTime, content)
+	f.disposition = "attachment"
+	return f
+}
+
+// FileFS constructs a response for the named file in the file system. A
+// missing file is returned as an *Error with the 404 status.
+func FileFS(r *http.Request, fsys fs.FS, name string) Encoder {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return NewError(http.StatusNotFound, "file %s not found", name)
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return NewError(http.StatusNotFound, "file %s not found", name)
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		f.Close()
+		return NewError(http.StatusInternalServerError, "file %s is not seekable", name)
+	}
+
+	return File(r, path.Base(name), info.ModTime(), content)
+}
+
+// WithETag returns a copy of the response that uses the entity tag. The
+// value must include the quotes, for example `"v1"` or `W/"v1"`.
+func (f FileResponse) WithETag(etag string) FileResponse {
+	f.etag = etag
+	return f
+}
+
+func (f FileResponse) HTTPStatus() int { return http.StatusOK }
+
+// Encode implements the Encoder interface by reading the whole content. It's
+// only used when the response is not streamed.
+func (f FileResponse) Encode() ([]byte, string, error) {
+	if c, ok := f.content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	data, err := io.ReadAll(f.content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(f.name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	return data, contentType, nil
+}
+
+// Stream implements the Streamer interface.
+func (f FileResponse) Stream(ctx context.Context, w http.ResponseWriter) error {
+	if c, ok := f.content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	h := w.Header()
+
+	etag := f.etag
+	if etag == "" && !f.modTime.IsZero() {
+		size, err := f.content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf("file: seek: %w", err)
+		}
+		if _, err := f.content.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("file: seek: %w", err)
+		}
+		etag = fmt.Sprintf(`W/"%x-%x"`, f.modTime.UnixNano(), size)
+	}
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+
+	if f.disposition != "" && f.name != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType(f.disposition, map[string]string{"filename": path.Base(f.name)}))
+	}
+
+	// ServeContent handles Range, If-Range, If-None-Match, If-Modified-Since
+	// and the content type.
+	http.ServeContent(w, f.r, f.name, f.modTime, f.content)
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// TemplateConfig defines where the templates are loaded from. Every page is
+// parsed together with all the layouts and partials so pages can define the
+// blocks used by the layouts.
+type TemplateConfig struct {
+	// FS holds the templates, usually an embed.FS. Use os.DirFS with Reload
+	// to pick up changes while developing.
+	FS fs.FS
+
+	// PagesDir, LayoutsDir and PartialsDir are the directories in FS. Pages
+	// are named by their path relative to PagesDir without the extension,
+	// for example "users/list".
+	PagesDir    string
+	LayoutsDir  string
+	PartialsDir string
+
+	// Layout is the name of the template executed for every page. When empty
+	// the page itself is executed.
+	Layout string
+
+	// Ext is the extension of the template files. Defaults to ".html".
+	Ext string
+
+	// Funcs are added to every template. The nonce function returning the
+	// Content-Security-Policy nonce of the request is always available.
+	Funcs template.FuncMap
+
+	// Reload parses the templates on every render, intended for develop mode.
+	Reload bool
+}
+
+// Templates is a registry of parsed HTML templates.
+type Templates struct {
+	cfg   TemplateConfig
+	pages map[string]*page
+}
+
+// page is a parsed page. The parsed template is never executed so it can be
+// cloned, and the clones are kept for later renders since each render needs
+// its own nonce function.
+type page struct {
+	tmpl   *template.Template
+	clones sync.Pool
+}
+
+// pageClone is a clone of a page whose nonce function returns the nonce of
+// the render using it.
+type pageClone struct {
+	tmpl  *template.Template
+	nonce string
+}
+
+// get returns a clone that isn't used by another render.
+func (p *page) get() (*pageClone, error) {
+	if c, ok := p.clones.Get().(*pageClone); ok {
+		return c, nil
+	}
+
+	tmpl, err := p.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	c := pageClone{tmpl: tmpl}
+	tmpl.Funcs(template.FuncMap{
+		"nonce": func() string { return c.nonce },
+	})
+
+	return &c, nil
+}
+
+// put makes the clone available to later renders.
+func (p *page) put(c *pageClone) {
+	c.nonce = ""
+	p.clones.Put(c)
+}
+
+// NewTemplates parses the templates described by the configuration.
+func NewTemplates(cfg TemplateConfig) (*Templates, error) {
+	if cfg.Ext == "" {
+		cfg.Ext = ".html"
+	}
+
+	t := Templates{
+		cfg: cfg,
+	}
+
+	pages, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	t.pages = pages
+
+	return &t, nil
+}
+
+// parse builds one template set per page.
+func (t *Templates) parse() (map[string]*page, error) {
+	funcs := template.FuncMap{
+		"nonce": func() string { return "" },
+	}
+	for k, v := range t.cfg.Funcs {
+		funcs[k] = v
+	}
+
+	var shared []string
+	for _, dir := range []string{t.cfg.LayoutsDir, t.cfg.PartialsDir} {
+		files, err := t.files(dir)
+		if err != nil {
+			return nil, err
+		}
+		shared = append(shared, files...)
+	}
+
+	pageFiles, err := t.files(t.cfg.PagesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*page, len(pageFiles))
+	for _, file := range pageFiles {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, strings.TrimSuffix(t.cfg.PagesDir, "/")+"/"), t.cfg.Ext)
+
+		tmpl := template.New(path.Base(file)).Funcs(funcs)
+		if len(shared) > 0 {
+			if tmpl, err = tmpl.ParseFS(t.cfg.FS, shared...); err != nil {
+				return nil, fmt.Errorf("templates: parse %s: %w", name, err)
+			}
+		}
+		if tmpl, err = tmpl.ParseFS(t.cfg.FS, file); err != nil {
+			return nil, fmt.Errorf("templates: parse %s: %w", name, err)
+		}
+
+		pages[name] = &page{tmpl: tmpl}
+	}
+
+	return pages, nil
+}
+
+// files returns the template files below the directory.
+func (t *Templates) files(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	var files []string
+	err := fs.WalkDir(t.cfg.FS, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, t.cfg.Ext) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("templates: walk %s: %w", dir, err)
+	}
+
+	return files, nil
+}
+
+// Execute renders the page into the writer. The nonce is returned by the
+// nonce template function.
+func (t *Templates) Execute(w io.Writer, name string, data any, nonce string) error {
+	pages := t.pages
+	if t.cfg.Reload {
+		var err error
+		if pages, err = t.parse(); err != nil {
+			return err
+		}
+	}
+
+	p, exists := pages[name]
+	if !exists {
+		return fmt.Errorf("templates: page %q not found", name)
+	}
+
+	c, err := p.get()
+	if err != nil {
+		return fmt.Errorf("templates: clone %s: %w", name, err)
+	}
+	defer p.put(c)
+	c.nonce = nonce
+
+	root := p.tmpl.Name()
+	if t.cfg.Layout != "" {
+		root = t.cfg.Layout
+	}
+
+	if err := c.tmpl.ExecuteTemplate(w, root, data); err != nil {
+		return fmt.Errorf("templates: execute %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+
+// RenderResponse is a page rendered from the application templates.
+type RenderResponse struct {
+	Status int
+	Data   []byte
+}
+
+// Render renders the named page with the templates set on the application
+// and the Content-Security-Policy nonce of the request. The page is rendered
+// before the handler returns, so a failure is returned as an *Error with the
+// 500 status that goes through the middleware like any other error.
+func Render(ctx context.Context, status int, name string, data any) Encoder {
+	t := getTemplates(ctx)
+	if t == nil {
+		return NewError(http.StatusInternalServerError, "render: templates not set on the app")
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, name, data, Nonce(ctx)); err != nil {
+		return NewError(http.StatusInternalServerError, "render: %s", err)
+	}
+
+	return RenderResponse{Status: status, Data: b.Bytes()}
+}
+
+func (rr RenderResponse) HTTPStatus() int { return rr.Status }
+
+// Encode implements the Encoder interface.
+func (rr RenderResponse) Encode() ([]byte, string, error) {
+	return rr.Data, "text/html; charset=utf-8", nil
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestTemplates(t *testing.T) *Templates {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<script nonce="{{nonce}}"></script>{{template "content" .}}`)},
+		"pages/home.html":   {Data: []byte(`{{define "content"}}hello {{.}}{{end}}`)},
+	}
+
+	tmpls, err := NewTemplates(TemplateConfig{
+		FS:         fsys,
+		PagesDir:   "pages",
+		LayoutsDir: "layouts",
+		Layout:     "base.html",
+	})
+	if err != nil {
+		t.Fatalf("new templates: %s", err)
+	}
+
+	return tmpls
+}
+
+func TestTemplatesExecute(t *testing.T) {
+	tmpls := newTestTemplates(t)
+
+	tests := []struct {
+		name  string
+		page  string
+		nonce string
+		want  string
+	}{
+		{name: "first render", page: "home", nonce: "a", want: `<script nonce="a"></script>hello bob`},
+		{name: "reused clone", page: "home", nonce: "b", want: `<script nonce="b"></script>hello bob`},
+		{name: "no nonce", page: "home", want: `<script nonce=""></script>hello bob`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tmpls.Execute(&b, tt.page, "bob", tt.nonce); err != nil {
+				t.Fatalf("execute: %s", err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := tmpls.Execute(&bytes.Buffer{}, "missing", nil, ""); err == nil {
+		t.Error("got nil error, want an error for a missing page")
+	}
+}
+
+func TestTemplatesExecuteConcurrent(t *testing.T) {
+	tmpls := newTestTemplates(t)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			nonce := fmt.Sprint(i)
+
+			var b bytes.Buffer
+			if err := tmpls.Execute(&b, "home", "bob", nonce); err != nil {
+				t.Errorf("execute %d: %s", i, err)
+				return
+			}
+
+			want := fmt.Sprintf(`<script nonce="%s"></script>hello bob`, nonce)
+			if got := b.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
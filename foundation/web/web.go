@@ -2,28 +2,12 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
-)
 
-var (
-	allowMethods = []string{
-		http.MethodGet,
-		http.MethodOptions,
-		http.MethodPost,
-		http.MethodPatch,
-		http.MethodPut,
-		http.MethodDelete,
-	}
-	allowHeaders = []string{
-		"Accept",
-		"Content-Type",
-		"Content-Length",
-		"Accept-Encoding",
-		"X-CSRF-Token",
-		"Authorization",
-	}
-	exposeHeader = []string{}
+	"github.com/nutchapon-m/web-server/foundation/tracer"
 )
 
 type Encoder interface {
@@ -32,84 +16,280 @@ type Encoder interface {
 
 type HandlerFunc func(ctx context.Context, r *http.Request) Encoder
 
+// DefaultMaxBodySize is the request body limit used when the application
+// doesn't set one.
+const DefaultMaxBodySize int64 = 4 << 20
+
 type Logger func(ctx context.Context, message string, args ...any)
 
 type App struct {
-	log     Logger
-	mux     *http.ServeMux
-	mw      []MidFunc
-	origins []string
+	log              Logger
+	mux              *http.ServeMux
+	mw               []MidFunc
+	cors             *corsPolicy
+	routeCORS        map[string]*corsPolicy
+	methods          []string
+	notFound         HandlerFunc
+	methodNotAllowed HandlerFunc
+	maxBodySize      int64
+	templates        *Templates
+	tracer           *tracer.Tracer
 }
 
 func NewApp(log Logger, mw ...MidFunc) *App {
 	mux := http.NewServeMux()
 	return &App{
-		mux: mux,
-		log: log,
-		mw:  mw,
+		mux:              mux,
+		log:              log,
+		mw:               mw,
+		routeCORS:        make(map[string]*corsPolicy),
+		notFound:         defaultNotFound,
+		methodNotAllowed: defaultMethodNotAllowed,
+		maxBodySize:      DefaultMaxBodySize,
 	}
 }
 
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if a.origins != nil {
-		reqOrigin := r.Header.Get("Origin")
-		for _, origin := range a.origins {
-			if origin == "*" || origin == reqOrigin {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				break
-			}
-		}
+	_, pattern := a.mux.Handler(r)
 
-		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowMethods, ", "))
-		w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowHeaders, ", "))
-		w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposeHeader, ", "))
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Max-Age", "86400")
-
-		if r.Method != http.MethodOptions {
-			w.Header().Set("Vary", "Origin")
-		} else {
-			w.Header().Set("Vary", "Origin")
-			w.Header().Set("Vary", "Access-Control-Request-Method")
-			w.Header().Set("Vary", "Access-Control-Allow-Headers")
-			w.WriteHeader(http.StatusNoContent)
+	// The mux has no match when the path is unknown or when the path is only
+	// registered for other methods, so the other methods are probed to tell
+	// a 404 from a 405.
+	var allow []string
+	if pattern == "" {
+		pattern, allow = a.allowed(r)
+	}
+
+	if cors := a.corsPolicy(pattern); cors != nil {
+		if preflight := cors.apply(w, r); preflight {
 			return
 		}
 	}
-	w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
+
+	// Requests that don't match any pattern are sent through the application
+	// middleware so they are logged and encoded like any other response.
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+
+		handler := wrapMiddleware(a.mw, a.methodNotAllowed)
+		a.handle(handler)(w, r)
+		return
+	}
+
+	if pattern == "" {
+		handler := wrapMiddleware(a.mw, a.notFound)
+		a.handle(handler)(w, r)
+		return
+	}
 
 	a.mux.ServeHTTP(w, r)
 }
 
-func (a *App) EnableCORS(origins []string) {
-	a.origins = origins
+// EnableCORS sets the CORS policy applied to every route that doesn't have
+// its own policy.
+func (a *App) EnableCORS(policy CORSPolicy) {
+	a.cors = policy.compile()
+}
+
+// RouteCORS sets a CORS policy for the specified path that overrides the
+// application policy.
+func (a *App) RouteCORS(path string, policy CORSPolicy) {
+	a.routeCORS[path] = policy.compile()
+}
+
+// corsPolicy returns the policy for the matched mux pattern.
+func (a *App) corsPolicy(pattern string) *corsPolicy {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+
+	if cors, exists := a.routeCORS[pattern]; exists {
+		return cors
+	}
+
+	return a.cors
+}
+
+// MaxBodySize sets the maximum number of bytes that can be read from a
+// request body. A value of zero or less disables the limit. Routes can
+// override the limit with the LimitBody middleware.
+func (a *App) MaxBodySize(n int64) {
+	a.maxBodySize = n
+}
+
+// Templates sets the templates used by Render.
+func (a *App) Templates(t *Templates) {
+	a.templates = t
+}
+
+// Tracer sets the tracer used to start a span for every request. The trace
+// of the caller is continued when the request has a traceparent header.
+func (a *App) Tracer(t *tracer.Tracer) {
+	a.tracer = t
 }
 
+// NotFound sets the handler used when no route matches the request. The
+// handler is wrapped by the application middleware.
+func (a *App) NotFound(handler HandlerFunc) {
+	a.notFound = handler
+}
+
+// MethodNotAllowed sets the handler used when a path is registered but not
+// for the method of the request. The Allow header is already set on the
+// writer when the handler is called and the handler is wrapped by the
+// application middleware.
+func (a *App) MethodNotAllowed(handler HandlerFunc) {
+	a.methodNotAllowed = handler
+}
+
+// HandlerFunc sets a handler function for a given HTTP method and path pair
+// to the application server mux. The same path can be registered for
+// multiple methods and GET routes also answer HEAD requests.
 func (a *App) HandlerFunc(method, group, path string, handler HandlerFunc, mw ...MidFunc) {
-	handler = wrapMiddleware(mw, handler)
+	handler = wrapMiddleware(mw, traceHandler(handler))
 	handler = wrapMiddleware(a.mw, handler)
 
-	h := func(w http.ResponseWriter, r *http.Request) {
-		// enforce HTTP method
-		if r.Method != method {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
+	pattern := path
+	if group != "" {
+		pattern = group + path
+	}
+
+	a.mux.HandleFunc(fmt.Sprintf("%s %s", method, pattern), a.handle(handler))
+
+	if !slices.Contains(a.methods, method) {
+		a.methods = append(a.methods, method)
+	}
+}
 
+// handle converts the handler into a http.HandlerFunc that responds with
+// the encoder returned by the handler.
+func (a *App) handle(handler HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := setWriter(r.Context(), w)
+		ctx = setValues(ctx)
+
+		if a.templates != nil {
+			ctx = setTemplates(ctx, a.templates)
+		}
+
+		if a.maxBodySize > 0 {
+			limitBody(w, r, a.maxBodySize)
+		}
+
+		var span *tracer.Span
+		if a.tracer != nil {
+			ctx = tracer.Extract(ctx, r.Header)
+			ctx, span = a.tracer.Start(ctx, spanName(r), tracer.SpanKindServer)
+			span.SetAttributes("http.method", r.Method, "http.route", routePath(r.Pattern), "url.path", r.URL.Path)
+			defer span.End()
+		}
 
 		resp := handler(ctx, r)
+		traceResponse(span, resp)
 
 		if err := Respond(ctx, w, resp); err != nil {
-			a.log(ctx, "web-response")
+			a.log(ctx, "web-response", "err", err)
 			return
 		}
 	}
+}
 
-	pattern := path
-	if group != "" {
-		pattern = group + path
+// traceHandler starts a span around the route handler so the time spent in
+// the handler can be told apart from the time spent in the middleware.
+func traceHandler(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, r *http.Request) Encoder {
+		ctx, span := tracer.Start(ctx, "handler "+r.Pattern, tracer.SpanKindInternal)
+		defer span.End()
+
+		resp := handler(ctx, r)
+		if err, ok := resp.(error); ok {
+			span.SetError(err)
+		}
+
+		return resp
 	}
+}
+
+// traceResponse records the status of the response in the request span.
+// Only server errors mark the span as failed.
+func traceResponse(span *tracer.Span, resp Encoder) {
+	if span == nil {
+		return
+	}
+
+	statusCode := http.StatusOK
+	switch v := resp.(type) {
+	case httpStatus:
+		statusCode = v.HTTPStatus()
+	case error:
+		statusCode = http.StatusInternalServerError
+	default:
+		if resp == nil {
+			statusCode = http.StatusNoContent
+		}
+	}
+	span.SetAttributes("http.status_code", statusCode)
+
+	if statusCode >= http.StatusInternalServerError {
+		message := http.StatusText(statusCode)
+		if err, ok := resp.(error); ok {
+			message = err.Error()
+		}
+		span.SetStatus(tracer.StatusError, message)
+	}
+}
+
+// spanName names the request span after the matched route.
+func spanName(r *http.Request) string {
+	if r.Pattern == "" {
+		return r.Method
+	}
+
+	return r.Method + " " + routePath(r.Pattern)
+}
+
+// routePath returns the path of a mux pattern without its method.
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+
+	return pattern
+}
+
+// allowed probes the mux with every method registered on the application
+// and returns the pattern matched for the path of the request along with
+// the methods it is registered for, sorted for the Allow header.
+func (a *App) allowed(r *http.Request) (string, []string) {
+	var pattern string
+	var methods []string
+
+	for _, method := range a.methods {
+		if method == r.Method {
+			continue
+		}
+
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+
+		if _, p := a.mux.Handler(probe); p != "" {
+			pattern = p
+			methods = append(methods, method)
+		}
+	}
+
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	slices.Sort(methods)
+
+	return pattern, methods
+}
+
+func defaultNotFound(ctx context.Context, r *http.Request) Encoder {
+	return JSON(http.StatusNotFound, nil)
+}
 
-	a.mux.HandleFunc(pattern, h)
+func defaultMethodNotAllowed(ctx context.Context, r *http.Request) Encoder {
+	return JSON(http.StatusMethodNotAllowed, nil)
 }
@@ -0,0 +1,46 @@
+package websocket
+
+import "fmt"
+
+// CloseCode represents the status code of a close frame.
+type CloseCode uint16
+
+// Close codes defined by RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure           CloseCode = 1000
+	CloseGoingAway               CloseCode = 1001
+	CloseProtocolError           CloseCode = 1002
+	CloseUnsupportedData         CloseCode = 1003
+	CloseNoStatusReceived        CloseCode = 1005
+	CloseAbnormalClosure         CloseCode = 1006
+	CloseInvalidFramePayloadData CloseCode = 1007
+	ClosePolicyViolation         CloseCode = 1008
+	CloseMessageTooBig           CloseCode = 1009
+	CloseMandatoryExtension      CloseCode = 1010
+	CloseInternalServerErr       CloseCode = 1011
+)
+
+// valid reports whether the code can be sent in a close frame.
+func (c CloseCode) valid() bool {
+	switch {
+	case c >= 1000 && c <= 1003:
+		return true
+	case c >= 1007 && c <= 1011:
+		return true
+	case c >= 3000 && c <= 4999:
+		return true
+	}
+
+	return false
+}
+
+// CloseError is returned by ReadMessage when the connection is closed.
+type CloseError struct {
+	Code CloseCode
+	Text string
+}
+
+// Error implements the error interface.
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
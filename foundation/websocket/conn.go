@@ -0,0 +1,383 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType represents the type of a data message.
+type MessageType int
+
+// The data message types.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Frame opcodes defined by RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const (
+	finBit  = 0x80
+	rsvBits = 0x70
+	maskBit = 0x80
+
+	maxControlPayload = 125
+)
+
+// ErrClosed is returned when writing to a connection after a close frame
+// has been sent.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn represents a server side WebSocket connection. Reads must be done from
+// a single goroutine, writes are safe for concurrent use.
+type Conn struct {
+	conn           net.Conn
+	br             *bufio.Reader
+	subprotocol    string
+	maxMessageSize int64
+
+	writeMu    sync.Mutex
+	closeSent  bool
+	closeOnce  sync.Once
+	done       chan struct{}
+	pongWait   time.Duration
+	pongHandle func(data []byte)
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, subprotocol string, maxMessageSize int64) *Conn {
+	return &Conn{
+		conn:           conn,
+		br:             br,
+		subprotocol:    subprotocol,
+		maxMessageSize: maxMessageSize,
+		done:           make(chan struct{}),
+	}
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for future reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler sets a function called for every pong received.
+func (c *Conn) SetPongHandler(fn func(data []byte)) {
+	c.pongHandle = fn
+}
+
+// =============================================================================
+
+// ReadMessage reads the next data message, answering pings and handling
+// fragmented messages. When the client closes the connection a *CloseError
+// is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var msgType MessageType
+	var msg []byte
+
+	for {
+		h, err := c.readHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if h.opcode >= opClose {
+			if !h.fin || h.length > maxControlPayload {
+				return 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+			}
+		} else if h.length > c.maxMessageSize-int64(len(msg)) {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+
+		payload, err := c.readPayload(h)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch h.opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil && !errors.Is(err, ErrClosed) {
+				return 0, nil, err
+			}
+			continue
+
+		case opPong:
+			if c.pongWait > 0 {
+				c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+			}
+			if c.pongHandle != nil {
+				c.pongHandle(payload)
+			}
+			continue
+
+		case opClose:
+			return 0, nil, c.handleClose(payload)
+
+		case opText, opBinary:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			msgType = MessageType(h.opcode)
+
+		case opContinuation:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		msg = append(msg, payload...)
+
+		if h.fin {
+			if msgType == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid utf-8")
+			}
+			return msgType, msg, nil
+		}
+	}
+}
+
+type frameHeader struct {
+	fin    bool
+	opcode byte
+	length int64
+	mask   [4]byte
+}
+
+func (c *Conn) readHeader() (frameHeader, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return frameHeader{}, err
+	}
+
+	h := frameHeader{
+		fin:    b[0]&finBit != 0,
+		opcode: b[0] & 0x0f,
+		length: int64(b[1] & 0x7f),
+	}
+
+	if b[0]&rsvBits != 0 {
+		return h, c.fail(CloseProtocolError, "reserved bits set")
+	}
+
+	// Frames sent by a client must always be masked.
+	if b[1]&maskBit == 0 {
+		return h, c.fail(CloseProtocolError, "frame not masked")
+	}
+
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		// The most significant bit of the length must be zero.
+		n := binary.BigEndian.Uint64(b[:8])
+		if n&(1<<63) != 0 {
+			return h, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		h.length = int64(n)
+	}
+
+	if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+		return h, err
+	}
+
+	return h, nil
+}
+
+func (c *Conn) readPayload(h frameHeader) ([]byte, error) {
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= h.mask[i%4]
+	}
+
+	return payload, nil
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	ce := CloseError{Code: CloseNoStatusReceived}
+
+	switch {
+	case len(payload) == 1:
+		c.fail(CloseProtocolError, "invalid close payload")
+		return &ce
+
+	case len(payload) >= 2:
+		ce.Code = CloseCode(binary.BigEndian.Uint16(payload))
+		ce.Text = string(payload[2:])
+
+		if !ce.Code.valid() {
+			c.fail(CloseProtocolError, "invalid close code")
+			return &ce
+		}
+		if !utf8.ValidString(ce.Text) {
+			c.fail(CloseInvalidFramePayloadData, "invalid utf-8")
+			return &ce
+		}
+	}
+
+	// Echo the close frame to complete the closing handshake.
+	code := ce.Code
+	if code == CloseNoStatusReceived {
+		code = CloseNormalClosure
+	}
+	c.Close(code, "")
+
+	return &ce
+}
+
+// fail closes the connection with the code and returns the matching error.
+func (c *Conn) fail(code CloseCode, text string) error {
+	c.Close(code, text)
+	c.close()
+
+	return &CloseError{Code: code, Text: text}
+}
+
+// =============================================================================
+
+// WriteMessage writes a data message.
+func (c *Conn) WriteMessage(msgType MessageType, data []byte) error {
+	if msgType != TextMessage && msgType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", msgType)
+	}
+
+	return c.writeFrame(byte(msgType), data)
+}
+
+// WriteText writes a text message.
+func (c *Conn) WriteText(text string) error {
+	return c.writeFrame(opText, []byte(text))
+}
+
+// Ping sends a ping with the data.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: ping payload too large")
+	}
+
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a close frame with the code and reason. The connection is
+// released once the client answers or the handler returns.
+func (c *Conn) Close(code CloseCode, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	err := c.writeFrame(opClose, payload)
+
+	c.writeMu.Lock()
+	c.closeSent = true
+	c.writeMu.Unlock()
+
+	return err
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	b := make([]byte, 0, len(payload)+10)
+	b = append(b, finBit|opcode)
+
+	n := len(payload)
+	switch {
+	case n <= 125:
+		b = append(b, byte(n))
+	case n <= 0xffff:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	b = append(b, payload...)
+
+	if _, err := c.conn.Write(b); err != nil {
+		return fmt.Errorf("websocket: write: %w", err)
+	}
+
+	return nil
+}
+
+// keepAlive pings the client at the interval and expects a pong before the
+// next ping is due.
+func (c *Conn) keepAlive(interval time.Duration) {
+	c.pongWait = 2 * interval
+	c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.Ping(nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// close releases the underlying network connection.
+func (c *Conn) close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+
+	return err
+}
@@ -0,0 +1,339 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// clientFrame encodes a frame as a client sends it, masked unless unmasked
+// is set.
+func clientFrame(fin bool, opcode byte, payload []byte, unmasked bool) []byte {
+	var b []byte
+
+	first := opcode
+	if fin {
+		first |= finBit
+	}
+	b = append(b, first)
+
+	var mask byte = maskBit
+	if unmasked {
+		mask = 0
+	}
+
+	n := len(payload)
+	switch {
+	case n <= 125:
+		b = append(b, mask|byte(n))
+	case n <= 0xffff:
+		b = append(b, mask|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, mask|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+
+	key := [4]byte{0x12, 0x34, 0x56, 0x78}
+	if !unmasked {
+		b = append(b, key[:]...)
+	}
+	for i, v := range payload {
+		if !unmasked {
+			v ^= key[i%4]
+		}
+		b = append(b, v)
+	}
+
+	return b
+}
+
+// readFrame reads a frame sent by the server, which must not be masked.
+func readFrame(r io.Reader) (frame, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return frame{}, err
+	}
+	if b[1]&maskBit != 0 {
+		return frame{}, errors.New("server frame is masked")
+	}
+
+	f := frame{fin: b[0]&finBit != 0, opcode: b[0] & 0x0f}
+
+	n := uint64(b[1] & 0x7f)
+	switch n {
+	case 126:
+		if _, err := io.ReadFull(r, b[:2]); err != nil {
+			return frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return frame{}, err
+		}
+		n = binary.BigEndian.Uint64(b[:8])
+	}
+
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+
+	return f, nil
+}
+
+// pipe returns a server connection and the client end of a net.Pipe. The
+// frames the server writes are collected until the pipe is closed.
+func pipe(t *testing.T, maxMessageSize int64) (*Conn, net.Conn, <-chan []frame) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	conn := newConn(server, bufio.NewReader(server), "", maxMessageSize)
+	t.Cleanup(func() { conn.close() })
+
+	received := make(chan []frame, 1)
+	go func() {
+		var frames []frame
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				received <- frames
+				return
+			}
+			frames = append(frames, f)
+		}
+	}()
+
+	return conn, client, received
+}
+
+func closePayload(code CloseCode, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	huge := bytes.Repeat([]byte("b"), 70_000)
+
+	tests := []struct {
+		name     string
+		max      int64
+		frames   [][]byte
+		msgType  MessageType
+		msg      []byte
+		code     CloseCode
+		closeMsg string
+		replies  []frame
+	}{
+		{
+			name:    "text",
+			frames:  [][]byte{clientFrame(true, opText, []byte("hello"), false)},
+			msgType: TextMessage,
+			msg:     []byte("hello"),
+		},
+		{
+			name:    "16 bit length",
+			frames:  [][]byte{clientFrame(true, opBinary, long, false)},
+			msgType: BinaryMessage,
+			msg:     long,
+		},
+		{
+			name:    "64 bit length",
+			frames:  [][]byte{clientFrame(true, opBinary, huge, false)},
+			msgType: BinaryMessage,
+			msg:     huge,
+		},
+		{
+			name: "fragmented with a ping",
+			frames: [][]byte{
+				clientFrame(false, opText, []byte("hel"), false),
+				clientFrame(true, opPing, []byte("p"), false),
+				clientFrame(true, opContinuation, []byte("lo"), false),
+			},
+			msgType: TextMessage,
+			msg:     []byte("hello"),
+			replies: []frame{{fin: true, opcode: opPong, payload: []byte("p")}},
+		},
+		{
+			name:     "close",
+			frames:   [][]byte{clientFrame(true, opClose, closePayload(CloseGoingAway, "bye"), false)},
+			code:     CloseGoingAway,
+			closeMsg: "bye",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseGoingAway, "")}},
+		},
+		{
+			name:    "close without code",
+			frames:  [][]byte{clientFrame(true, opClose, nil, false)},
+			code:    CloseNoStatusReceived,
+			replies: []frame{{fin: true, opcode: opClose, payload: closePayload(CloseNormalClosure, "")}},
+		},
+		{
+			name:     "unmasked",
+			frames:   [][]byte{clientFrame(true, opText, []byte("hello"), true)},
+			code:     CloseProtocolError,
+			closeMsg: "frame not masked",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseProtocolError, "frame not masked")}},
+		},
+		{
+			name:     "too big",
+			max:      4,
+			frames:   [][]byte{clientFrame(true, opText, []byte("hello"), false)},
+			code:     CloseMessageTooBig,
+			closeMsg: "message too big",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseMessageTooBig, "message too big")}},
+		},
+		{
+			name: "too big once assembled",
+			max:  4,
+			frames: [][]byte{
+				clientFrame(false, opText, []byte("hel"), false),
+				clientFrame(true, opContinuation, []byte("lo"), false),
+			},
+			code:     CloseMessageTooBig,
+			closeMsg: "message too big",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseMessageTooBig, "message too big")}},
+		},
+		{
+			name:     "invalid utf-8",
+			frames:   [][]byte{clientFrame(true, opText, []byte{0xff, 0xfe}, false)},
+			code:     CloseInvalidFramePayloadData,
+			closeMsg: "invalid utf-8",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseInvalidFramePayloadData, "invalid utf-8")}},
+		},
+		{
+			name:     "unexpected continuation",
+			frames:   [][]byte{clientFrame(true, opContinuation, []byte("x"), false)},
+			code:     CloseProtocolError,
+			closeMsg: "unexpected continuation frame",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseProtocolError, "unexpected continuation frame")}},
+		},
+		{
+			name:     "fragmented control frame",
+			frames:   [][]byte{clientFrame(false, opPing, []byte("p"), false)},
+			code:     CloseProtocolError,
+			closeMsg: "invalid control frame",
+			replies:  []frame{{fin: true, opcode: opClose, payload: closePayload(CloseProtocolError, "invalid control frame")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.max
+			if size == 0 {
+				size = 1 << 20
+			}
+			conn, client, received := pipe(t, size)
+
+			go func() {
+				for _, f := range tt.frames {
+					if _, err := client.Write(f); err != nil {
+						return
+					}
+				}
+			}()
+
+			msgType, msg, err := conn.ReadMessage()
+			conn.close()
+
+			if tt.code != 0 {
+				var ce *CloseError
+				if !errors.As(err, &ce) {
+					t.Fatalf("got error %v, want a close error", err)
+				}
+				if ce.Code != tt.code || ce.Text != tt.closeMsg {
+					t.Errorf("got close %d %q, want %d %q", ce.Code, ce.Text, tt.code, tt.closeMsg)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("read: %s", err)
+				}
+				if msgType != tt.msgType || !bytes.Equal(msg, tt.msg) {
+					t.Errorf("got %d %q, want %d %q", msgType, msg, tt.msgType, tt.msg)
+				}
+			}
+
+			replies := <-received
+			if len(replies) != len(tt.replies) {
+				t.Fatalf("got %d replies, want %d", len(replies), len(tt.replies))
+			}
+			for i, want := range tt.replies {
+				got := replies[i]
+				if got.fin != want.fin || got.opcode != want.opcode || !bytes.Equal(got.payload, want.payload) {
+					t.Errorf("reply %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	huge := bytes.Repeat([]byte("b"), 70_000)
+
+	conn, _, received := pipe(t, 1<<20)
+
+	go func() {
+		conn.WriteText("hello")
+		conn.WriteMessage(BinaryMessage, huge)
+		conn.Ping([]byte("p"))
+		conn.Close(CloseNormalClosure, "done")
+
+		if err := conn.WriteText("late"); !errors.Is(err, ErrClosed) {
+			t.Errorf("got error %v after close, want %v", err, ErrClosed)
+		}
+		conn.close()
+	}()
+
+	want := []frame{
+		{fin: true, opcode: opText, payload: []byte("hello")},
+		{fin: true, opcode: opBinary, payload: huge},
+		{fin: true, opcode: opPing, payload: []byte("p")},
+		{fin: true, opcode: opClose, payload: closePayload(CloseNormalClosure, "done")},
+	}
+
+	got := <-received
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].fin != want[i].fin || got[i].opcode != want[i].opcode || !bytes.Equal(got[i].payload, want[i].payload) {
+			t.Errorf("frame %d: got opcode %d with %d bytes, want opcode %d with %d bytes", i, got[i].opcode, len(got[i].payload), want[i].opcode, len(want[i].payload))
+		}
+	}
+}
+
+func TestWriteMessageInvalid(t *testing.T) {
+	conn, _, _ := pipe(t, 1<<20)
+
+	if err := conn.WriteMessage(MessageType(opPing), nil); err == nil {
+		t.Error("got nil error, want an error for a control opcode")
+	}
+	if err := conn.Ping(make([]byte, maxControlPayload+1)); err == nil {
+		t.Error("got nil error, want an error for a ping payload over 125 bytes")
+	}
+}
+
+func TestWithMaxMessageSize(t *testing.T) {
+	for _, n := range []int64{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic for %d, want a panic", n)
+				}
+			}()
+
+			WithMaxMessageSize(n)
+		}()
+	}
+}
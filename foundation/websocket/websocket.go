@@ -0,0 +1,255 @@
+// Package websocket provides an RFC 6455 WebSocket server implementation that
+// upgrades requests handled by web.App so the middleware of the route runs
+// before the upgrade.
+package websocket
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nutchapon-m/web-server/foundation/web"
+)
+
+// acceptGUID is the GUID defined by RFC 6455 to compute the accept key.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Options represent optional parameters for an upgrade.
+type Options struct {
+	origins        []string
+	checkOrigin    func(r *http.Request) bool
+	subprotocols   []string
+	maxMessageSize int64
+	pingInterval   time.Duration
+}
+
+// WithOrigins sets the origins allowed to connect. The value "*" allows any
+// origin. By default only the origin matching the request host is allowed.
+func WithOrigins(origins ...string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.origins = origins
+	}
+}
+
+// WithCheckOrigin replaces the origin check with a custom function.
+func WithCheckOrigin(fn func(r *http.Request) bool) func(opts *Options) {
+	return func(opts *Options) {
+		opts.checkOrigin = fn
+	}
+}
+
+// WithSubprotocols sets the subprotocols supported by the server in order of
+// preference.
+func WithSubprotocols(protocols ...string) func(opts *Options) {
+	return func(opts *Options) {
+		opts.subprotocols = protocols
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a message read from the
+// client. Larger messages close the connection with CloseMessageTooBig. It
+// panics when the size isn't positive since every message would be
+// rejected.
+func WithMaxMessageSize(n int64) func(opts *Options) {
+	if n <= 0 {
+		panic(fmt.Sprintf("websocket: max message size must be positive, got %d", n))
+	}
+
+	return func(opts *Options) {
+		opts.maxMessageSize = n
+	}
+}
+
+// WithPingInterval sends a ping at the interval and closes the connection
+// when no pong is received before the next ping is due.
+func WithPingInterval(d time.Duration) func(opts *Options) {
+	return func(opts *Options) {
+		opts.pingInterval = d
+	}
+}
+
+// =============================================================================
+
+// HandlerFunc handles a WebSocket connection. The connection is closed when
+// the function returns.
+type HandlerFunc func(ctx context.Context, conn *Conn) error
+
+// Handler returns a web.HandlerFunc that upgrades the request and calls the
+// handler with the connection. Since it's a regular web handler, the app,
+// group and route middleware run before the upgrade and the connection is
+// handled inside the middleware chain. An error of the handler closes the
+// connection with CloseInternalServerErr and is returned through the
+// middleware so it's logged, while nothing more is written to the client.
+func Handler(handler HandlerFunc, options ...func(opts *Options)) web.HandlerFunc {
+	return func(ctx context.Context, r *http.Request) web.Encoder {
+		conn, err := Upgrade(ctx, r, options...)
+		if err != nil {
+			var e *web.Error
+			if errors.As(err, &e) {
+				return e
+			}
+			return web.NewError(http.StatusInternalServerError, "websocket: upgrade: %s", err)
+		}
+		defer conn.close()
+
+		err = handler(ctx, conn)
+
+		// The client ending the connection is not a failure of the handler.
+		var ce *CloseError
+		if errors.As(err, &ce) {
+			switch ce.Code {
+			case CloseNormalClosure, CloseGoingAway, CloseNoStatusReceived:
+				err = nil
+			}
+		}
+
+		if err != nil {
+			conn.Close(CloseInternalServerErr, "internal server error")
+			return web.NewError(http.StatusInternalServerError, "websocket: handler: %s", err)
+		}
+
+		conn.Close(CloseNormalClosure, "")
+		return web.NewNoResponse()
+	}
+}
+
+// Upgrade validates the opening handshake, hijacks the connection and
+// returns the WebSocket connection. Handshake failures are returned as an
+// *web.Error and nothing has been written to the client.
+func Upgrade(ctx context.Context, r *http.Request, options ...func(opts *Options)) (*Conn, error) {
+	opts := Options{
+		maxMessageSize: 1 << 20,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	w := web.GetWriter(ctx)
+	if w == nil {
+		return nil, web.NewError(http.StatusInternalServerError, "websocket: response writer not available")
+	}
+
+	if r.Method != http.MethodGet {
+		return nil, web.NewError(http.StatusMethodNotAllowed, "websocket: handshake requires GET")
+	}
+
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, web.NewError(http.StatusBadRequest, "websocket: not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, web.NewError(http.StatusBadRequest, "websocket: unsupported version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, web.NewError(http.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key")
+	}
+
+	checkOrigin := opts.checkOrigin
+	if checkOrigin == nil {
+		checkOrigin = func(r *http.Request) bool { return allowOrigin(r, opts.origins) }
+	}
+	if !checkOrigin(r) {
+		return nil, web.NewError(http.StatusForbidden, "websocket: origin not allowed")
+	}
+
+	subprotocol := selectSubprotocol(r, opts.subprotocols)
+
+	netConn, brw, err := web.Hijack(ctx)
+	if err != nil {
+		return nil, web.NewError(http.StatusInternalServerError, "websocket: hijack: %s", err)
+	}
+
+	// Clear the deadlines the server set for the HTTP request.
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	fmt.Fprintf(&b, "Sec-WebSocket-Accept: %s\r\n", acceptKey(key))
+	if subprotocol != "" {
+		fmt.Fprintf(&b, "Sec-WebSocket-Protocol: %s\r\n", subprotocol)
+	}
+	b.WriteString("\r\n")
+
+	if _, err := brw.WriteString(b.String()); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+
+	conn := newConn(netConn, brw.Reader, subprotocol, opts.maxMessageSize)
+	if opts.pingInterval > 0 {
+		conn.keepAlive(opts.pingInterval)
+	}
+
+	return conn, nil
+}
+
+// =============================================================================
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether the comma separated header contains the
+// token, ignoring case.
+func headerContains(h http.Header, name string, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func allowOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if len(origins) > 0 {
+		return slices.Contains(origins, "*") || slices.ContainsFunc(origins, func(o string) bool {
+			return strings.EqualFold(o, origin)
+		})
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(v, ",") {
+			protocol = strings.TrimSpace(protocol)
+			if slices.Contains(supported, protocol) {
+				return protocol
+			}
+		}
+	}
+
+	return ""
+}
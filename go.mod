@@ -2,10 +2,7 @@ module github.com/nutchapon-m/web-server
 
 go 1.24.0
 
-require (
-	github.com/spf13/viper v1.21.0
-	golang.org/x/time v0.14.0
-)
+require github.com/spf13/viper v1.21.0
 
 require (
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
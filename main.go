@@ -16,12 +16,16 @@ import (
 	"github.com/nutchapon-m/web-server/app/sdk/mux"
 	"github.com/nutchapon-m/web-server/foundation/env"
 	"github.com/nutchapon-m/web-server/foundation/logger"
+	"github.com/nutchapon-m/web-server/foundation/metrics"
+	"github.com/nutchapon-m/web-server/foundation/tracer"
 	"github.com/nutchapon-m/web-server/foundation/web"
+	"github.com/spf13/viper"
 )
 
 var (
-	build = flag.String("mode", "develop", "Service running on mode: develop or release")
-	port  = flag.String("port", "8000", "Service port")
+	build     = flag.String("mode", "develop", "Service running on mode: develop or release")
+	port      = flag.String("port", "8000", "Service port")
+	adminPort = flag.String("admin-port", "8010", "Admin port serving the metrics")
 )
 
 func main() {
@@ -38,7 +42,7 @@ func main() {
 	// -------------------------------------------------------------------------
 	// Start service
 
-	log := logger.New(os.Stdout, logger.LevelInfo, "WEB-API")
+	log := logger.New(os.Stdout, logger.LevelInfo, "WEB-API", tracer.LogIDs)
 	ctx := context.Background()
 	if err := run(ctx, log); err != nil {
 		log.Error(ctx, "startup", "err", err)
@@ -52,32 +56,70 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	log.Info(ctx, "startup", "GOMAXPROCS", runtime.GOMAXPROCS(0))
 
+	// -------------------------------------------------------------------------
+	// Tracing
+
+	// Spans are propagated to the called services and their IDs logged. Add
+	// tracer.WithExporter to record them.
+	trc := tracer.New("WEB-API")
+
+	// -------------------------------------------------------------------------
+	// Metrics
+
+	reg := metrics.NewRegistry()
+	reg.RegisterRuntime()
+
 	// -------------------------------------------------------------------------
 	// Configuration
 
 	cfg := mux.Config{
-		Build: *build,
-		Log:   log,
+		Build:      *build,
+		Log:        log,
+		CSRFSecret: []byte(viper.GetString("csrf.secret")),
+	}
+
+	handler, err := mux.WebAPI(cfg, buildRoutes(), mux.WithTracer(trc), mux.WithMetrics(reg))
+	if err != nil {
+		return err
 	}
 
-	var addr string
+	var addr, adminAddr string
 	if *build == "develop" {
 		addr = fmt.Sprintf("localhost:%s", *port)
+		adminAddr = fmt.Sprintf("localhost:%s", *adminPort)
 	} else {
 		addr = fmt.Sprintf(":%s", *port)
+		adminAddr = fmt.Sprintf(":%s", *adminPort)
 	}
 
 	server := http.Server{
 		Addr:         addr,
-		Handler:      mux.WebAPI(cfg, buildRoutes()),
+		Handler:      handler,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
+	adminMux := http.NewServeMux()
+	adminMux.Handle("GET /metrics", reg.Handler())
+
+	admin := http.Server{
+		Addr:         adminAddr,
+		Handler:      adminMux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	log.Info(ctx, "Admin running", "addr", adminAddr)
+	go func() {
+		if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error(ctx, "admin listen and serve", "err", err)
+		}
+	}()
+
 	log.Info(ctx, "Server running", "addr", addr)
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -93,6 +135,10 @@ func run(ctx context.Context, log *logger.Logger) error {
 		log.Error(ctx, "shutdown error", "err", err)
 	}
 
+	if err := admin.Shutdown(ctx); err != nil {
+		log.Error(ctx, "admin shutdown error", "err", err)
+	}
+
 	log.Info(ctx, "shutdown")
 	return nil
 }
@@ -111,7 +157,9 @@ type add struct{}
 
 func (add) Add(app *web.App, cfg mux.Config) {
 
-	app.HandlerFunc(http.MethodGet, "/api", "/test-error", func(ctx context.Context, r *http.Request) web.Encoder {
+	api := app.Group("/api")
+
+	api.Get("/test-error", func(ctx context.Context, r *http.Request) web.Encoder {
 		fieldErr := errs.NewFieldErrors(errs.InvalidArgument)
 		fieldErr.Add("value", errors.New("field value is required"))
 		return fieldErr